@@ -27,17 +27,19 @@ package echo_http_cache
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
 	"io"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
-	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -71,6 +73,124 @@ type (
 		IncludePaths               []string
 		IncludePathsWithExpiration map[string]time.Duration // key: path, value: expiration //IncludePathsWithExpiration has higher priority
 		ExcludePaths               []string
+
+		// Rules is the ordered list of cache rules. The first rule matching a request decides
+		// whether and how it is cached. The paths of IncludePaths, IncludePathsWithExpiration,
+		// IncludePOSTPaths and ExcludePaths are substrings of the request URL, matched after Rules.
+		Rules []CacheRule
+
+		// CacheableStatusCodes lists the response status codes that can be cached.
+		// When empty, every status code below 400 is cacheable.
+		CacheableStatusCodes []int
+
+		// NegativeExpiration is the expiration used for 4xx responses.
+		// When zero, the regular expiration is used.
+		NegativeExpiration time.Duration
+
+		// RespectCacheControl enables RFC 9111 Cache-Control handling. Response directives
+		// (no-store, private, max-age, s-maxage, Expires) decide whether and how long a response
+		// is stored, with the configured expiration used as default and upper bound. Request
+		// directives (no-cache, no-store, max-age, min-fresh, only-if-cached) are applied on lookup.
+		RespectCacheControl bool
+
+		// KeyGenerator returns the value the cache key is computed from, or false to
+		// skip caching for the request. HEAD requests must map to the key of the GET
+		// request to be answered from it. Defaults to DefaultKeyGenerator.
+		KeyGenerator func(c echo.Context) (string, bool)
+
+		// FillCacheOnHEAD serves a HEAD request that misses the cache through the GET
+		// handler, so that the response is cached. Otherwise the HEAD request is passed
+		// to the next handler. HEAD requests are answered from cached GET responses either way.
+		FillCacheOnHEAD bool
+
+		// IncludePOSTPaths lists the paths whose POST requests are cached. The request body
+		// is part of the cache key, with JSON bodies canonicalized first.
+		IncludePOSTPaths []string
+
+		// MaxRequestBodySize is the largest POST request body read to compute the cache key.
+		// Requests with a larger body are not cached. Defaults to 64KB.
+		MaxRequestBodySize int64
+
+		// StaleWhileRevalidate is the period after expiration during which a cached response
+		// is still served while a single background request refreshes it.
+		StaleWhileRevalidate time.Duration
+
+		// RevalidateTimeout is the deadline of a background refresh. Another refresh can
+		// start once it expires, even if the handler did not return. Defaults to 30 seconds.
+		RevalidateTimeout time.Duration
+
+		// StaleIfError is the period after expiration during which a cached response is
+		// served when the handler returns an error, panics or responds with a 5xx status.
+		StaleIfError time.Duration
+
+		// CoalesceRequests lets a single request run the handler on a cache miss while the
+		// concurrent requests for the same key wait for its response.
+		CoalesceRequests bool
+
+		// CoalesceTimeout is how long a coalesced request waits for the response before
+		// running the handler itself. Defaults to 5 seconds.
+		CoalesceTimeout time.Duration
+
+		// Metrics collects the statistics of the middleware, such as the number of
+		// coalesced requests and their wait time.
+		Metrics *CacheMetrics
+
+		// TagHeader is the response header listing the tags of the response, separated by
+		// spaces or commas. Tags can also be set with SetCacheTags. Defaults to Surrogate-Key.
+		TagHeader string
+
+		// InvalidateOnUnsafeMethods invalidates the cached GET responses of a path, with any
+		// query string, after a successful (2xx) POST, PUT, PATCH or DELETE request to it.
+		// POST requests cached with IncludePOSTPaths do not invalidate anything.
+		InvalidateOnUnsafeMethods bool
+
+		// InvalidatePaths maps the path or route of an unsafe request to the paths it also
+		// invalidates, e.g. "/api/users/:id": {"/api/users?*"}. Route parameters of the
+		// mapped paths are replaced by the values of the request.
+		InvalidatePaths map[string][]string
+
+		// Compression compresses the cached response bodies with CompressionGzip or
+		// CompressionZstd. On a hit, the compressed body is sent to the clients accepting
+		// its encoding and decompressed for the others. Bodies already encoded by the
+		// handler are stored as they are.
+		Compression string
+
+		// CompressionMinSize is the smallest body compressed. Defaults to 1KB.
+		CompressionMinSize int
+
+		// CacheStatusName is the name of the cache in the RFC 9211 Cache-Status header
+		// of the responses. Defaults to echo-http-cache.
+		CacheStatusName string
+
+		// DisableCacheStatus disables the Cache-Status header.
+		DisableCacheStatus bool
+
+		// XCache enables the X-Cache header, set to HIT, MISS, STALE or BYPASS.
+		XCache bool
+
+		// MaxBodySize is the largest response body captured to be cached. Larger responses,
+		// as well as flushed, hijacked and text/event-stream responses, are sent as they are
+		// written and not cached. Defaults to 4MB, a negative value disables the limit.
+		MaxBodySize int64
+
+		// Codec encodes the cached responses. Defaults to BinaryCodec.
+		Codec Codec
+
+		// ShouldCache decides whether a response with a cacheable status code is cached.
+		// Defaults to CacheIfNonEmptyBody. CacheIfStatus, CacheIfContentType and CacheIfAll
+		// build other predicates.
+		ShouldCache ShouldCacheFunc
+
+		// ErrorHandler is called with the errors of the store, wrapped in a StoreError, and
+		// with the panics of background refreshes. The middleware fails open: a failing lookup
+		// is a miss and a failing write is skipped. Defaults to DefaultCacheErrorHandler.
+		ErrorHandler func(c echo.Context, err error)
+
+		// Hooks are called with the decisions of the middleware.
+		Hooks CacheHooks
+
+		// Tracer traces the lookups and fills of the middleware. Disabled when nil.
+		Tracer CacheTracer
 	}
 
 	// CacheResponse is the cached response data structure.
@@ -78,6 +198,9 @@ type (
 		// URL is URL
 		URL string `json:"url"`
 
+		// StatusCode is the cached response status code.
+		StatusCode int `json:"statusCode,omitempty"`
+
 		// Header is the cached response header.
 		Header http.Header `json:"header"`
 
@@ -94,14 +217,33 @@ type (
 		// Frequency is the count of times a cached response is accessed.
 		// Used for LFU and MFU algorithms.
 		Frequency int `json:"frequency"`
+
+		// VaryHeaders is set on vary markers only and lists the request headers
+		// that select a variant of the response.
+		VaryHeaders []string `json:"varyHeaders,omitempty"`
+
+		// Tags are the tags the cached response can be invalidated by.
+		Tags []string `json:"tags,omitempty"`
+
+		// ContentEncoding is the compression of Body applied by the cache.
+		ContentEncoding string `json:"contentEncoding,omitempty"`
 	}
 )
 
 var (
 	// DefaultCacheConfig defines default values for CacheConfig
 	DefaultCacheConfig = CacheConfig{
-		Skipper:    middleware.DefaultSkipper,
-		Expiration: 3 * time.Minute,
+		Skipper:            middleware.DefaultSkipper,
+		Expiration:         3 * time.Minute,
+		MaxRequestBodySize: 64 * 1024,
+		RevalidateTimeout:  30 * time.Second,
+		CoalesceTimeout:    5 * time.Second,
+		TagHeader:          "Surrogate-Key",
+		CompressionMinSize: 1024,
+		CacheStatusName:    "echo-http-cache",
+		MaxBodySize:        4 * 1024 * 1024,
+		Codec:              BinaryCodec{},
+		ErrorHandler:       DefaultCacheErrorHandler,
 	}
 )
 
@@ -132,150 +274,736 @@ func CacheWithConfig(config CacheConfig) echo.MiddlewareFunc {
 	if config.Expiration < 1 {
 		panic("Cache expiration must be provided")
 	}
+	if config.KeyGenerator == nil {
+		config.KeyGenerator = DefaultKeyGenerator
+	}
+	if config.MaxRequestBodySize == 0 {
+		config.MaxRequestBodySize = DefaultCacheConfig.MaxRequestBodySize
+	}
+	if config.RevalidateTimeout == 0 {
+		config.RevalidateTimeout = DefaultCacheConfig.RevalidateTimeout
+	}
+	if config.CoalesceTimeout == 0 {
+		config.CoalesceTimeout = DefaultCacheConfig.CoalesceTimeout
+	}
+	if config.Metrics == nil {
+		config.Metrics = &CacheMetrics{}
+	}
+	if config.TagHeader == "" {
+		config.TagHeader = DefaultCacheConfig.TagHeader
+	}
+	if config.CompressionMinSize == 0 {
+		config.CompressionMinSize = DefaultCacheConfig.CompressionMinSize
+	}
+	if config.CacheStatusName == "" {
+		config.CacheStatusName = DefaultCacheConfig.CacheStatusName
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = DefaultCacheConfig.MaxBodySize
+	}
+	if config.Codec == nil {
+		config.Codec = DefaultCacheConfig.Codec
+	}
+	if config.ShouldCache == nil {
+		config.ShouldCache = CacheIfNonEmptyBody
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = DefaultCacheErrorHandler
+	}
+	if config.Tracer == nil {
+		config.Tracer = noopTracer{}
+	}
+
+	m := &cacheMiddleware{
+		config:  config,
+		store:   ToCacheStoreV2(config.Store),
+		rules:   compileRules(config),
+		flights: newFlightGroup(),
+	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			if config.Skipper(c) {
-				return next(c)
-			}
+			return m.handle(c, next)
+		}
+	}
+}
+
+// cacheMiddleware holds the configuration and the state shared by the requests of a cache middleware.
+type cacheMiddleware struct {
+	config CacheConfig
+	rules  []*CacheRule
+	store  CacheStoreV2
+
+	// keys of the responses being refreshed in the background
+	revalidating sync.Map
+
+	// handler calls in flight, when requests are coalesced
+	flights *flightGroup
+}
 
-			if config.isExcludePaths(c.Request().URL.String()) {
-				return next(c)
+func (m *cacheMiddleware) handle(c echo.Context, next echo.HandlerFunc) error {
+	if m.config.Skipper(c) {
+		return next(c)
+	}
+
+	method := c.Request().Method
+	if method != http.MethodGet && method != http.MethodHead && method != http.MethodPost {
+		return m.handleUnsafe(c, next)
+	}
+
+	rule := matchRule(m.rules, c)
+	if rule == nil {
+		// let the Route middleware of the route cache the request
+		c.Set(contextKeyMiddleware, m)
+	}
+	if rule == nil || rule.Exclude {
+		if rule != nil {
+			m.setCacheStatus(c, XCacheBypass, "fwd=bypass")
+			m.bypass(c, rule, 0, "excluded")
+		}
+		return m.handleUnsafe(c, next)
+	}
+
+	return m.serve(c, next, rule)
+}
+
+// serve answers the request from the cache or through the handler, following the rule.
+func (m *cacheMiddleware) serve(c echo.Context, next echo.HandlerFunc, rule *CacheRule) error {
+	method := c.Request().Method
+	keyValue, ok := rule.keyValue(c)
+	if !ok {
+		m.setCacheStatus(c, XCacheBypass, "fwd=bypass")
+		m.bypass(c, rule, 0, "key")
+		return next(c)
+	}
+	var reqBody []byte
+	if method == http.MethodPost {
+		if reqBody, ok = readRequestBody(c.Request(), m.config.MaxRequestBodySize); !ok {
+			m.setCacheStatus(c, XCacheBypass, "fwd=bypass")
+			m.bypass(c, rule, 0, "request-body")
+			return next(c)
+		}
+		keyValue += "\n" + requestBodyDigest(c.Request().Header.Get(echo.HeaderContentType), reqBody)
+	}
+	key := hashKey(keyValue)
+
+	var reqCacheControl cacheControl
+	if m.config.RespectCacheControl {
+		reqCacheControl = requestCacheControl(c.Request())
+	}
+
+	var cached CacheResponse
+	var staleIfError *CacheResponse
+	var storedKey uint64
+	var level string
+	found := false
+	fwd := "fwd=request"
+	if !isRevalidation(c.Request()) && !reqCacheControl.has("no-cache") {
+		ctx, span := m.config.Tracer.Start(c.Request().Context(), SpanLookup)
+		span.SetAttribute(AttributeCacheKey, keyAsString(key))
+		cached, storedKey, level, found = m.lookup(c, ctx, rule, key)
+		if found {
+			span.SetAttribute(AttributeCacheStatus, "hit")
+			span.SetAttribute(AttributeCacheEntrySize, len(cached.Body))
+			if level != "" {
+				span.SetAttribute(AttributeCacheLevel, level)
 			}
-			if !config.isIncludePaths(c.Request().URL.String()) {
-				return next(c)
+		} else {
+			span.SetAttribute(AttributeCacheStatus, "miss")
+		}
+		span.End(nil)
+		fwd = "fwd=uri-miss"
+		if storedKey != key {
+			fwd = "fwd=vary-miss"
+		}
+	}
+
+	if found {
+		now := time.Now()
+
+		// not expired. return response from the cache
+		if !isExpired(now, cached.Expiration) && reqCacheControl.acceptsCached(cached, now) {
+			// restore the response in the cache
+			cached.LastAccess = now
+			cached.Frequency++
+
+			m.set(c, rule, storedKey, cached)
+			m.setHitStatus(c, XCacheHit, cached, level, now)
+			notify(m.config.Hooks.OnHit, c, CacheEvent{Key: storedKey, Rule: rule, Level: level, Entry: entryInfo(cached)})
+			writeCachedResponse(c, cached)
+			return nil
+		}
+
+		// expired but within the stale-while-revalidate window. return the stale response
+		// and refresh it in the background
+		if m.config.isStaleWhileRevalidate(now, cached.Expiration) && reqCacheControl.acceptsCached(cached, now) {
+			if _, loaded := m.revalidating.LoadOrStore(key, struct{}{}); !loaded {
+				go m.revalidate(c.Echo(), revalidationRequest(c.Request(), reqBody), key)
 			}
 
-			if c.Request().Method == http.MethodGet {
-				sortURLParams(c.Request().URL)
-				key := generateKey(c.Request().Method, c.Request().URL.String())
-
-				if cachedResponse, ok := config.Store.Get(key); ok {
-					response := toCacheResponse(cachedResponse)
-					now := time.Now()
-
-					// not expired. return response from the cache
-					if !isExpired(now, response.Expiration) {
-						// restore the response in the cache
-						response.LastAccess = now
-						response.Frequency++
-
-						config.Store.Set(key, response.bytes(), response.Expiration)
-						for k, v := range response.Header {
-							c.Response().Header().Set(k, strings.Join(v, ","))
-						}
-						c.Response().WriteHeader(http.StatusOK)
-						c.Response().Write(response.Body)
-						return nil
-					}
-				}
+			c.Response().Header().Set("Warning", `110 - "Response is Stale"`)
+			m.setHitStatus(c, XCacheStale, cached, level, now)
+			notify(m.config.Hooks.OnHit, c, CacheEvent{Key: storedKey, Rule: rule, Reason: "stale-while-revalidate", Level: level, Entry: entryInfo(cached)})
+			writeCachedResponse(c, cached)
+			return nil
+		}
 
-				// Response
-				resBody := new(bytes.Buffer)
-				mw := io.MultiWriter(c.Response().Writer, resBody)
-				writer := &bodyDumpResponseWriter{Writer: mw, ResponseWriter: c.Response().Writer}
-				c.Response().Writer = writer
+		// expired but within the stale-if-error window. keep the stale response in case the handler fails
+		if m.config.isStaleIfError(now, cached.Expiration) {
+			staleIfError = &cached
+		}
+		fwd = "fwd=stale"
+	}
 
-				if err := next(c); err != nil {
-					c.Error(err)
-				}
+	if reqCacheControl.has("only-if-cached") {
+		m.setCacheStatus(c, XCacheMiss, fwd, "fwd-status=504")
+		notify(m.config.Hooks.OnMiss, c, CacheEvent{Key: key, Rule: rule, Reason: strings.TrimPrefix(fwd, "fwd=")})
+		return c.NoContent(http.StatusGatewayTimeout)
+	}
+
+	if method == http.MethodHead {
+		if !m.config.FillCacheOnHEAD {
+			m.setCacheStatus(c, XCacheBypass, "fwd=bypass")
+			m.bypass(c, rule, key, "head")
+			return next(c)
+		}
+
+		// fill the cache through the GET handler and answer with its headers
+		getReq := c.Request().Clone(c.Request().Context())
+		getReq.Method = http.MethodGet
+		getReq.Body = http.NoBody
+		getReq.ContentLength = 0
 
-				if writer.statusCode < http.StatusBadRequest {
-					body := resBody.Bytes()
-					now := time.Now()
-
-					response := CacheResponse{
-						Body:       body,
-						URL:        c.Request().URL.String(),
-						Header:     writer.Header(),
-						Expiration: config.getExpiration(now, c.Request().URL.String()),
-						LastAccess: now,
-						Frequency:  1,
-					}
-
-					if !isAllFieldsEmpty(body) {
-						config.Store.Set(key, response.bytes(), response.Expiration)
-					}
+		recorded := replayRequest(c.Echo(), getReq)
+		for _, h := range []string{HeaderCacheStatus, HeaderXCache} {
+			if v := recorded.Header().Get(h); v != "" {
+				c.Response().Header().Set(h, v)
+			}
+		}
+		writeCachedResponse(c, CacheResponse{
+			StatusCode: recorded.statusCode,
+			Header:     recorded.Header(),
+			Body:       recorded.body.Bytes(),
+		})
+		return nil
+	}
+
+	// concurrent misses are coalesced: a single request runs the handler while the others wait for its response
+	var call *flightCall
+	leader := false
+	if m.config.CoalesceRequests {
+		call, leader = m.flights.join(key)
+		if leader {
+			defer func() {
+				// the handler did not produce a response to share
+				if leader {
+					m.flights.finish(key, call, nil, key)
 				}
+			}()
+		} else {
+			start := time.Now()
+			if response, ok := call.wait(m.config.CoalesceTimeout); ok && call.matches(key, c.Request()) {
+				m.config.Metrics.IncrementCoalesced(time.Since(start))
+				m.setCacheStatus(c, XCacheMiss, fwd, "collapsed")
+				notify(m.config.Hooks.OnMiss, c, CacheEvent{Key: call.key, Rule: rule, Reason: "collapsed", Entry: entryInfo(*response)})
+				writeCachedResponse(c, *response)
 				return nil
 			}
+		}
+	}
+
+	m.setCacheStatus(c, XCacheMiss, fwd)
+	notify(m.config.Hooks.OnMiss, c, CacheEvent{Key: key, Rule: rule, Reason: strings.TrimPrefix(fwd, "fwd=")})
+
+	// Response. It is held back while a stale response can replace it on failure
+	var baseHeader http.Header
+	if staleIfError != nil {
+		baseHeader = c.Response().Header().Clone()
+	}
+	writer := newBodyDumpResponseWriter(c.Response().Writer, staleIfError != nil, m.config.MaxBodySize)
+	c.Response().Writer = writer
+
+	// the handler and the store calls are traced as children of the fill span
+	reqCtx := c.Request().Context()
+	fillCtx, fillSpan := m.config.Tracer.Start(reqCtx, SpanFill)
+	fillSpan.SetAttribute(AttributeCacheKey, keyAsString(key))
+	if fillCtx != reqCtx {
+		c.SetRequest(c.Request().WithContext(fillCtx))
+		defer func() {
+			c.SetRequest(c.Request().WithContext(reqCtx))
+		}()
+	}
+
+	handlerErr := runHandler(next, c, staleIfError != nil)
+	if handlerErr != nil {
+		c.Error(handlerErr)
+	}
+
+	if writer.buffered {
+		if writer.statusCode >= http.StatusInternalServerError {
+			// discard the failed response and serve the stale one
+			for k := range writer.Header() {
+				writer.Header().Del(k)
+			}
+			for k, v := range baseHeader {
+				writer.Header()[k] = v
+			}
+			c.Response().Writer = writer.ResponseWriter
+			c.Response().Committed = false
+			c.Response().Size = 0
+			c.Response().Header().Set("Warning", `111 - "Revalidation Failed"`)
+			m.setHitStatus(c, XCacheStale, *staleIfError, level, time.Now(), "fwd=stale", fmt.Sprintf("fwd-status=%d", writer.statusCode))
+			notify(m.config.Hooks.OnHit, c, CacheEvent{Key: storedKey, Rule: rule, Reason: "stale-if-error", Level: level, Entry: entryInfo(*staleIfError)})
+			writeCachedResponse(c, *staleIfError)
+			fillSpan.SetAttribute(AttributeStatusCode, writer.statusCode)
+			fillSpan.SetAttribute(AttributeCacheStatus, "stale")
+			fillSpan.End(handlerErr)
 			return nil
 		}
+		writer.flushBuffered()
+	}
+
+	response, storedKey := m.storeResponse(c, rule, key, writer, reqCacheControl)
+	fillSpan.SetAttribute(AttributeStatusCode, c.Response().Status)
+	fillSpan.SetAttribute(AttributeCacheStatus, "miss")
+	fillSpan.SetAttribute(AttributeCacheStored, response != nil)
+	if response != nil {
+		fillSpan.SetAttribute(AttributeCacheEntrySize, len(response.Body))
+	}
+	fillSpan.End(handlerErr)
+	if leader {
+		m.flights.finish(key, call, response, storedKey)
+		leader = false
 	}
+	return nil
 }
 
-func (c *CacheConfig) isIncludePaths(URL string) bool {
-	for _, p := range c.IncludePaths {
-		if strings.Contains(URL, p) {
-			return true
-		}
+// storeResponse stores the response recorded by writer when it is cacheable. It returns
+// the stored response and the key it is stored under, or nil when nothing is stored.
+func (m *cacheMiddleware) storeResponse(c echo.Context, rule *CacheRule, key uint64, writer *bodyDumpResponseWriter, reqCacheControl cacheControl) (*CacheResponse, uint64) {
+	if writer.uncacheable {
+		m.bypass(c, rule, key, "uncacheable-body")
+		return nil, key
+	}
+
+	statusCode := writer.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	if !rule.isCacheableStatusCode(statusCode) {
+		m.bypass(c, rule, key, "status")
+		return nil, key
+	}
+
+	body := writer.body.Bytes()
+	now := time.Now()
+
+	header := writer.Header().Clone()
+	header.Del(HeaderCacheStatus)
+	header.Del(HeaderXCache)
+	if header.Get("Date") == "" {
+		header.Set("Date", now.UTC().Format(http.TimeFormat))
+	}
+	setValidators(header, body, now)
+
+	expiration := rule.getExpiration(now, statusCode)
+	storable := true
+	if m.config.RespectCacheControl {
+		expiration, storable = responseExpiration(c.Request(), header, now, expiration)
+		storable = storable && !reqCacheControl.has("no-store")
+	}
+
+	varyHeaders := variantHeaders(header)
+	if isVaryAll(varyHeaders) {
+		storable = false
+	}
+
+	response := CacheResponse{
+		Body:       body,
+		URL:        c.Request().URL.String(),
+		StatusCode: statusCode,
+		Header:     header,
+		Expiration: expiration,
+		LastAccess: now,
+		Frequency:  1,
+		Tags:       responseTags(c, rule.Tags, header, m.config.TagHeader),
+	}
+	if m.config.InvalidateOnUnsafeMethods {
+		response.Tags = append(response.Tags, pathTag(c.Request().URL.Path))
+	}
+
+	if !storable {
+		m.bypass(c, rule, key, "no-store")
+		return nil, key
+	}
+	if !m.config.ShouldCache(c, statusCode, header, body) {
+		m.bypass(c, rule, key, "should-cache")
+		return nil, key
 	}
 
-	for k, _ := range c.IncludePathsWithExpiration {
-		if strings.Contains(URL, k) {
-			return true
+	if len(varyHeaders) > 0 {
+		marker := CacheResponse{
+			URL:         response.URL,
+			Expiration:  response.Expiration,
+			LastAccess:  now,
+			Frequency:   1,
+			VaryHeaders: varyHeaders,
+		}
+		if !m.set(c, rule, key, marker) {
+			return nil, key
+		}
+		key = varyKey(key, varyHeaders, c.Request().Header)
+	}
+	m.config.compressResponse(&response)
+	if !m.set(c, rule, key, response) {
+		return nil, key
+	}
+	if tagStore, ok := StoreAs[CacheTagStore](m.config.Store); ok {
+		// the path tag also indexes the keys by path for PurgePrefix and PurgePattern
+		tags := response.Tags
+		if !m.config.InvalidateOnUnsafeMethods {
+			tags = append(tags[:len(tags):len(tags)], pathTag(c.Request().URL.Path))
 		}
+		tagStore.SetTags(key, tags, m.config.storeExpiration(response.Expiration))
 	}
-	return false
+	notify(m.config.Hooks.OnStore, c, CacheEvent{Key: key, Rule: rule, Entry: entryInfo(response)})
+	return &response, key
 }
 
-func (c *CacheConfig) isExcludePaths(URL string) bool {
-	for _, p := range c.ExcludePaths {
-		if strings.Contains(URL, p) {
-			return true
-		}
+// runHandler calls the handler. When recoverPanic is set, a panic is returned as an error.
+func runHandler(next echo.HandlerFunc, c echo.Context, recoverPanic bool) (err error) {
+	if recoverPanic {
+		defer func() {
+			if r := recover(); r != nil {
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				err = fmt.Errorf("handler panic: %v", r)
+			}
+		}()
 	}
-	return false
+	return next(c)
 }
 
-func (c *CacheConfig) getExpiration(now time.Time, URL string) time.Time {
-	for k, v := range c.IncludePathsWithExpiration {
-		if strings.Contains(URL, k) {
-			return now.Add(v)
-		}
+// writeCachedResponse writes the cached response to the client. HEAD requests get the
+// headers of the response, including its Content-Length, without the body.
+func writeCachedResponse(c echo.Context, response CacheResponse) {
+	encoding := negotiateEncoding(c, response)
+	if etag := response.Header.Get("ETag"); encoding != "" && etag != "" {
+		response.Header = response.Header.Clone()
+		response.Header.Set("ETag", encodedETag(etag, encoding))
+	}
+
+	if isNotModified(c.Request(), response) {
+		writeNotModified(c.Response(), response)
+		return
+	}
+
+	body, err := encodedBody(response, encoding)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	for k, v := range response.Header {
+		c.Response().Header().Set(k, strings.Join(v, ","))
+	}
+	if response.ContentEncoding != "" && !hasHeaderToken(c.Response().Header(), echo.HeaderVary, echo.HeaderAcceptEncoding) {
+		c.Response().Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+	}
+	if encoding != "" {
+		c.Response().Header().Set(echo.HeaderContentEncoding, encoding)
+	}
+	if c.Request().Method == http.MethodHead {
+		c.Response().Header().Set(echo.HeaderContentLength, strconv.Itoa(len(body)))
+		c.Response().WriteHeader(response.statusCode())
+		return
+	}
+	c.Response().WriteHeader(response.statusCode())
+	c.Response().Write(body)
+}
+
+// replayRequest serves the request through the echo instance, including its middleware,
+// and records the response instead of sending it to the client.
+func replayRequest(e *echo.Echo, req *http.Request) *captureResponseWriter {
+	recorded := &captureResponseWriter{header: http.Header{}}
+	e.ServeHTTP(recorded, req)
+
+	return recorded
+}
+
+// revalidate refreshes the cached response of the key in the background. The key is
+// released when the refresh returns or its deadline expires, and a panic of the handler
+// is reported to the ErrorHandler, since no server recovers it outside of a request.
+func (m *cacheMiddleware) revalidate(e *echo.Echo, req *http.Request, key uint64) {
+	defer m.revalidating.Delete(key)
+
+	ctx, cancel := context.WithTimeout(req.Context(), m.config.RevalidateTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				c := e.NewContext(req, &captureResponseWriter{header: http.Header{}})
+				m.config.ErrorHandler(c, fmt.Errorf("echo-http-cache: revalidation panic: %v", r))
+			}
+		}()
+		replayRequest(e, req)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+type revalidationContextKey struct{}
+
+// revalidationRequest returns a copy of the request, detached from the client, that
+// refreshes the cached response when replayed.
+func revalidationRequest(req *http.Request, body []byte) *http.Request {
+	ctx := context.WithValue(context.Background(), revalidationContextKey{}, true)
+	revalidation := req.Clone(ctx)
+	if revalidation.Method == http.MethodHead {
+		revalidation.Method = http.MethodGet
+	}
+	revalidation.Body = io.NopCloser(bytes.NewReader(body))
+	revalidation.ContentLength = int64(len(body))
+
+	return revalidation
+}
+
+// isRevalidation reports whether the request refreshes a cached response. The cached
+// response is not looked up for such requests.
+func isRevalidation(req *http.Request) bool {
+	return req.Context().Value(revalidationContextKey{}) != nil
+}
+
+// lookup returns the cached response for the request, the key it is stored under and the
+// store level it comes from, if known. When the key holds a vary marker, the variant selected
+// by the request headers is returned.
+func (m *cacheMiddleware) lookup(c echo.Context, ctx context.Context, rule *CacheRule, key uint64) (CacheResponse, uint64, string, bool) {
+	cachedResponse, level, ok := m.get(c, ctx, rule, key)
+	if !ok {
+		return CacheResponse{}, key, "", false
+	}
+
+	response, err := m.config.Codec.Decode(cachedResponse)
+	if err != nil {
+		return CacheResponse{}, key, "", false
+	}
+	if !response.isVaryMarker() {
+		return response, key, level, true
+	}
+
+	key = varyKey(key, response.VaryHeaders, c.Request().Header)
+	if cachedResponse, level, ok = m.get(c, ctx, rule, key); !ok {
+		return CacheResponse{}, key, "", false
 	}
+	if response, err = m.config.Codec.Decode(cachedResponse); err != nil {
+		return CacheResponse{}, key, "", false
+	}
+	return response, key, level, true
+}
+
+// set encodes and stores the response under the key. It returns false when the response
+// cannot be encoded or stored.
+func (m *cacheMiddleware) set(c echo.Context, rule *CacheRule, key uint64, response CacheResponse) bool {
+	data, err := m.config.Codec.Encode(response)
+	if err != nil {
+		return false
+	}
+	err = m.store.SetContext(c.Request().Context(), key, data, m.config.storeExpiration(response.Expiration))
+	if err != nil {
+		m.storeError(c, rule, &StoreError{Op: "set", Key: key, Err: err})
+		return false
+	}
+	return true
+}
+
+// get returns the cached data of the key and the store level it comes from, if known.
+// A failing store is reported and treated as a miss.
+func (m *cacheMiddleware) get(c echo.Context, ctx context.Context, rule *CacheRule, key uint64) ([]byte, string, bool) {
+	ctx, level := withLevelRecorder(ctx)
+	data, ok, err := m.store.GetContext(ctx, key)
+	if err != nil {
+		m.storeError(c, rule, &StoreError{Op: "get", Key: key, Err: err})
+		return nil, "", false
+	}
+	return data, *level, ok
+}
+
+// storeExpiration returns the time until which the store keeps a response that
+// expires at expiration, so that it can still be served stale.
+func (c *CacheConfig) storeExpiration(expiration time.Time) time.Time {
+	if c.StaleIfError > c.StaleWhileRevalidate {
+		return expiration.Add(c.StaleIfError)
+	}
+	return expiration.Add(c.StaleWhileRevalidate)
+}
+
+func (c *CacheConfig) isStaleWhileRevalidate(now, expiration time.Time) bool {
+	return c.StaleWhileRevalidate > 0 && !now.After(expiration.Add(c.StaleWhileRevalidate))
+}
 
-	return now.Add(c.Expiration)
+func (c *CacheConfig) isStaleIfError(now, expiration time.Time) bool {
+	return c.StaleIfError > 0 && !now.After(expiration.Add(c.StaleIfError))
 }
 
 type bodyDumpResponseWriter struct {
 	io.Writer
 	http.ResponseWriter
 	statusCode int
+
+	// body receives a copy of the response body. When buffered is set, the response
+	// is held back in body until flushBuffered is called.
+	body     *bytes.Buffer
+	buffered bool
+
+	// uncacheable is set once the response is known not to be cacheable: it is larger
+	// than maxBodySize, streamed or hijacked. The body is not captured anymore.
+	uncacheable bool
+	maxBodySize int64
+}
+
+func newBodyDumpResponseWriter(w http.ResponseWriter, buffered bool, maxBodySize int64) *bodyDumpResponseWriter {
+	writer := &bodyDumpResponseWriter{ResponseWriter: w, body: new(bytes.Buffer), buffered: buffered, maxBodySize: maxBodySize}
+	writer.Writer = io.MultiWriter(w, writer.body)
+	if buffered {
+		writer.Writer = writer.body
+	}
+	return writer
 }
 
 func (w *bodyDumpResponseWriter) WriteHeader(code int) {
 	w.statusCode = code
-	w.ResponseWriter.WriteHeader(code)
+	if isStreamingContentType(w.Header().Get(echo.HeaderContentType)) {
+		buffered := w.buffered
+		w.skip()
+		if buffered {
+			// the status code was sent with the held back response
+			return
+		}
+	}
+	if !w.buffered {
+		w.ResponseWriter.WriteHeader(code)
+	}
 }
 
 func (w *bodyDumpResponseWriter) Write(b []byte) (int, error) {
+	if !w.uncacheable && w.maxBodySize > 0 && int64(w.body.Len()+len(b)) > w.maxBodySize {
+		w.skip()
+	}
+	if w.uncacheable {
+		return w.ResponseWriter.Write(b)
+	}
 	return w.Writer.Write(b)
 }
 
+// Flush sends the response written so far. A flushed response is streamed and is not cached.
 func (w *bodyDumpResponseWriter) Flush() {
+	w.skip()
 	w.ResponseWriter.(http.Flusher).Flush()
 }
 
+// skip stops capturing the response, which is sent as it is written and not cached.
+func (w *bodyDumpResponseWriter) skip() {
+	if w.uncacheable {
+		return
+	}
+	w.flushBuffered()
+	w.uncacheable = true
+	w.body = new(bytes.Buffer)
+}
+
+// flushBuffered sends the held back response and writes the rest of the response
+// directly to the client.
+func (w *bodyDumpResponseWriter) flushBuffered() {
+	if !w.buffered {
+		return
+	}
+
+	w.buffered = false
+	w.Writer = io.MultiWriter(w.ResponseWriter, w.body)
+	if w.statusCode != 0 {
+		w.ResponseWriter.WriteHeader(w.statusCode)
+	}
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
 func (w *bodyDumpResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.uncacheable = true
 	return w.ResponseWriter.(http.Hijacker).Hijack()
 }
 
+// isStreamingContentType reports whether responses of the content type are streams, which are never cached.
+func isStreamingContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/event-stream"
+}
+
+// captureResponseWriter records a response without sending it to a client.
+type captureResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (w *captureResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *captureResponseWriter) WriteHeader(code int) {
+	if w.statusCode == 0 {
+		w.statusCode = code
+	}
+}
+
+func (w *captureResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
 // bytes converts CacheResponse data structure into bytes array.
 func (r CacheResponse) bytes() []byte {
 	data, _ := json.Marshal(r)
 	return data
 }
 
-// toCacheResponse converts bytes array into CacheResponse data structure.
-func toCacheResponse(b []byte) CacheResponse {
-	var r CacheResponse
-	dec := json.NewDecoder(bytes.NewReader(b))
-	dec.Decode(&r)
+// statusCode returns the cached status code. Entries stored before the status
+// code was recorded are replayed as 200 OK.
+func (r CacheResponse) statusCode() int {
+	if r.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return r.StatusCode
+}
 
+// toCacheResponse converts bytes array into CacheResponse data structure. Both the
+// BinaryCodec and JSONCodec formats are decoded, invalid entries being returned empty.
+func toCacheResponse(b []byte) CacheResponse {
+	r, _ := BinaryCodec{}.Decode(b)
 	return r
 }
 
+// DefaultKeyGenerator computes the cache key from the request method and the URL
+// with sorted query parameters. HEAD requests use the key of the GET request.
+func DefaultKeyGenerator(c echo.Context) (string, bool) {
+	method := c.Request().Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	sortURLParams(c.Request().URL)
+	return keyValue(method, c.Request().URL.String()), true
+}
+
 func sortURLParams(URL *url.URL) {
 	params := URL.Query()
 	for _, param := range params {
@@ -292,63 +1020,19 @@ func keyAsString(key uint64) string {
 }
 
 func generateKey(method, URL string) uint64 {
-	hash := fnv.New64a()
-	hash.Write([]byte(fmt.Sprintf("%s:%s", method, URL)))
-
-	return hash.Sum64()
+	return hashKey(keyValue(method, URL))
 }
 
-func isAllFieldsEmpty(inter any) bool {
-	val := reflect.ValueOf(inter)
-	if val.IsZero() {
-		return true
-	}
-
-	switch val.Kind() {
-	case reflect.Struct:
-		for i := 0; i < val.NumField(); i++ {
-			field := val.Field(i)
-			zeroValue := reflect.Zero(field.Type())
-			if reflect.DeepEqual(field.Interface(), zeroValue.Interface()) {
-				continue
-			}
-			return false
-		}
-	case reflect.Slice:
-		var dataMap map[string]any
-
-		if err := json.Unmarshal(inter.([]byte), &dataMap); err != nil {
-			// Failed to unmarshal JSON, assume not empty
-			return false
-		}
-		return isMapEmpty(dataMap)
-	}
-
-	return true
+func keyValue(method, URL string) string {
+	return fmt.Sprintf("%s:%s", method, URL)
 }
 
-func isMapEmpty(m map[string]any) bool {
-	for _, v := range m {
-		switch val := v.(type) {
-		case string:
-			if val != "" && val != "0001-01-01T00:00:00Z" {
-				return false
-			}
-		case int:
-			if val != 0 {
-				return false
-			}
-		case float64:
-			if val != 0 {
-				return false
-			}
-		case bool:
-			if val == false {
-				return false
-			}
-		}
-	}
-	return true
+// hashKey converts the value returned by a KeyGenerator into a cache key.
+func hashKey(value string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+
+	return hash.Sum64()
 }
 
 func isExpired(now, expiration time.Time) bool {
@@ -0,0 +1,415 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+type (
+	// CacheAdminConfig defines the config of the admin API.
+	CacheAdminConfig struct {
+		// Store is the store of the cache middleware.
+		Store CacheStore
+
+		// Skipper skips the authorization of some requests, such as the stats.
+		Skipper middleware.Skipper
+
+		// Authorizer authorizes the requests, returning an error such as echo.ErrUnauthorized
+		// or echo.ErrForbidden to deny them. Every request is authorized when it is nil.
+		Authorizer func(c echo.Context) error
+
+		// Codec decodes the cached responses. Defaults to BinaryCodec.
+		Codec Codec
+
+		// Metrics are the metrics of the cache middleware, added to the stats when set.
+		Metrics *CacheMetrics
+
+		// DefaultLimit is the number of keys listed per page when the request has no limit.
+		// Defaults to 100.
+		DefaultLimit int
+
+		// MaxLimit is the largest number of keys listed per page. Defaults to 1000.
+		MaxLimit int
+	}
+
+	// CacheKeyLister is implemented by the stores listing their keys.
+	CacheKeyLister interface {
+		// Keys returns up to about limit keys following the cursor, and the cursor of the
+		// next page, or "" on the last page. The first page has an empty cursor.
+		Keys(ctx context.Context, cursor string, limit int) ([]uint64, string, error)
+	}
+
+	// AdminStats is the response of the stats endpoint.
+	AdminStats struct {
+		// Entries is the number of cached responses, for the stores counting them.
+		Entries *int `json:"entries,omitempty"`
+
+		// Store are the statistics of a CacheTwoLevelStore.
+		Store *CacheStats `json:"store,omitempty"`
+
+		// Middleware are the statistics of CacheAdminConfig.Metrics.
+		Middleware *CacheStats `json:"middleware,omitempty"`
+
+		Evictions  *int64 `json:"evictions,omitempty"`
+		QueueDepth *int   `json:"queueDepth,omitempty"`
+	}
+
+	// AdminEntry is the response of the entry lookup endpoint.
+	AdminEntry struct {
+		Key             string      `json:"key"`
+		URL             string      `json:"url"`
+		StatusCode      int         `json:"statusCode,omitempty"`
+		Header          http.Header `json:"header,omitempty"`
+		Size            int         `json:"size"`
+		Expiration      time.Time   `json:"expiration"`
+		LastAccess      time.Time   `json:"lastAccess"`
+		Frequency       int         `json:"frequency"`
+		Tags            []string    `json:"tags,omitempty"`
+		ContentEncoding string      `json:"contentEncoding,omitempty"`
+
+		// VaryHeaders is set when the key holds a vary marker, the variants being
+		// stored under keys derived from these request headers.
+		VaryHeaders []string `json:"varyHeaders,omitempty"`
+	}
+
+	// AdminKeys is the response of the key listing endpoint.
+	AdminKeys struct {
+		Keys []string `json:"keys"`
+		Next string   `json:"next,omitempty"`
+	}
+
+	// AdminPurge is the response of the purge endpoints.
+	AdminPurge struct {
+		Purged int `json:"purged"`
+	}
+)
+
+// ErrKeysNotSupported is returned when the store does not list its keys.
+var ErrKeysNotSupported = errors.New("store does not list its keys")
+
+// DefaultCacheAdminConfig provides default configuration values for the admin API.
+var DefaultCacheAdminConfig = CacheAdminConfig{
+	Skipper:      middleware.DefaultSkipper,
+	Codec:        BinaryCodec{},
+	DefaultLimit: 100,
+	MaxLimit:     1000,
+}
+
+/*
+CacheAdmin mounts the admin API of the store on the group:
+
+	GET    /stats          statistics of the store
+	GET    /keys           keys of the store, paginated with the cursor and limit query parameters
+	GET    /entry          cached response of the url (or key) query parameter
+	DELETE /entry          releases the cached response of the url (or key) query parameter
+	DELETE /prefix         releases the cached responses whose URL path starts with the prefix query parameter
+	DELETE /pattern        releases the cached responses whose URL path matches the glob pattern query parameter
+	DELETE /tags           releases the cached responses with one of the tag query parameters
+	DELETE /levels/:level  clears the store, level being all, l1 or l2 for a CacheTwoLevelStore
+
+	CacheAdmin(e.Group("/admin/cache", middleware.BasicAuth(validator)), store)
+
+The url query parameter is the URL of a GET request, such as /products?page=2, whose key
+is the one of DefaultKeyGenerator. The endpoints which the store does not support answer
+501 Not Implemented.
+*/
+func CacheAdmin(g *echo.Group, store CacheStore) {
+	config := DefaultCacheAdminConfig
+	config.Store = store
+
+	CacheAdminWithConfig(g, config)
+}
+
+// CacheAdminWithConfig mounts the admin API on the group with a custom config.
+func CacheAdminWithConfig(g *echo.Group, config CacheAdminConfig) {
+	if config.Store == nil {
+		panic("Store configuration must be provided")
+	}
+	if config.Skipper == nil {
+		config.Skipper = DefaultCacheAdminConfig.Skipper
+	}
+	if config.Codec == nil {
+		config.Codec = DefaultCacheAdminConfig.Codec
+	}
+	if config.DefaultLimit == 0 {
+		config.DefaultLimit = DefaultCacheAdminConfig.DefaultLimit
+	}
+	if config.MaxLimit == 0 {
+		config.MaxLimit = DefaultCacheAdminConfig.MaxLimit
+	}
+
+	a := &cacheAdmin{config: config, store: ToCacheStoreV2(config.Store)}
+	authorize := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Authorizer != nil && !config.Skipper(c) {
+				if err := config.Authorizer(c); err != nil {
+					return err
+				}
+			}
+			return next(c)
+		}
+	}
+
+	g.GET("/stats", a.stats, authorize)
+	g.GET("/keys", a.keys, authorize)
+	g.GET("/entry", a.entry, authorize)
+	g.DELETE("/entry", a.purgeEntry, authorize)
+	g.DELETE("/prefix", a.purgePrefix, authorize)
+	g.DELETE("/pattern", a.purgePattern, authorize)
+	g.DELETE("/tags", a.purgeTags, authorize)
+	g.DELETE("/levels/:level", a.clearLevel, authorize)
+}
+
+type cacheAdmin struct {
+	config CacheAdminConfig
+	store  CacheStoreV2
+}
+
+// errNotImplemented is returned by the endpoints which the store does not support.
+var errNotImplemented = echo.NewHTTPError(http.StatusNotImplemented, "not supported by the store")
+
+func (a *cacheAdmin) stats(c echo.Context) error {
+	var stats AdminStats
+	store := a.config.Store
+
+	if sizer, ok := StoreAs[interface{ Size() int }](store); ok {
+		entries := sizer.Size()
+		stats.Entries = &entries
+	}
+	if statser, ok := StoreAs[interface{ GetStats() CacheStats }](store); ok {
+		storeStats := statser.GetStats()
+		stats.Store = &storeStats
+	}
+	if a.config.Metrics != nil {
+		middlewareStats := a.config.Metrics.GetStats()
+		stats.Middleware = &middlewareStats
+	}
+	if counter, ok := StoreAs[interface {
+		Evictions(ctx context.Context) (int64, error)
+	}](store); ok {
+		evictions, err := counter.Evictions(c.Request().Context())
+		if err == nil {
+			stats.Evictions = &evictions
+		}
+	}
+	if queue, ok := StoreAs[interface{ QueueDepth() int }](store); ok {
+		depth := queue.QueueDepth()
+		stats.QueueDepth = &depth
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (a *cacheAdmin) keys(c echo.Context) error {
+	lister, ok := StoreAs[CacheKeyLister](a.config.Store)
+	if !ok {
+		return errNotImplemented
+	}
+
+	limit := a.config.DefaultLimit
+	if value := c.QueryParam("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		limit = min(n, a.config.MaxLimit)
+	}
+
+	keys, next, err := lister.Keys(c.Request().Context(), c.QueryParam("cursor"), limit)
+	if errors.Is(err, ErrKeysNotSupported) {
+		return errNotImplemented
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+	}
+
+	response := AdminKeys{Keys: make([]string, len(keys)), Next: next}
+	for i, key := range keys {
+		response.Keys[i] = keyAsString(key)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (a *cacheAdmin) entry(c echo.Context) error {
+	key, err := requestedKey(c)
+	if err != nil {
+		return err
+	}
+
+	data, ok, err := a.store.GetContext(c.Request().Context(), key)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+	}
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "not cached")
+	}
+
+	response, err := a.config.Codec.Decode(data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, AdminEntry{
+		Key:             keyAsString(key),
+		URL:             response.URL,
+		StatusCode:      response.StatusCode,
+		Header:          response.Header,
+		Size:            len(response.Body),
+		Expiration:      response.Expiration,
+		LastAccess:      response.LastAccess,
+		Frequency:       response.Frequency,
+		Tags:            response.Tags,
+		ContentEncoding: response.ContentEncoding,
+		VaryHeaders:     response.VaryHeaders,
+	})
+}
+
+func (a *cacheAdmin) purgeEntry(c echo.Context) error {
+	key, err := requestedKey(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	exists, err := a.store.Exists(ctx, key)
+	if err == nil && exists {
+		err = a.store.Delete(ctx, key)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+	}
+
+	response := AdminPurge{}
+	if exists {
+		response.Purged = 1
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (a *cacheAdmin) purgePrefix(c echo.Context) error {
+	prefix := c.QueryParam("prefix")
+	if prefix == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "prefix is required")
+	}
+	return purgeResponse(c)(PurgePrefix(c.Request().Context(), a.config.Store, prefix))
+}
+
+func (a *cacheAdmin) purgePattern(c echo.Context) error {
+	pattern := c.QueryParam("pattern")
+	if pattern == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "pattern is required")
+	}
+	return purgeResponse(c)(PurgePattern(c.Request().Context(), a.config.Store, pattern))
+}
+
+// purgeResponse returns the function answering with the result of a purge.
+func purgeResponse(c echo.Context) func(purged int, err error) error {
+	return func(purged int, err error) error {
+		if errors.Is(err, ErrPurgeNotSupported) {
+			return errNotImplemented
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, AdminPurge{Purged: purged})
+	}
+}
+
+func (a *cacheAdmin) purgeTags(c echo.Context) error {
+	tags := c.QueryParams()["tag"]
+	if len(tags) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "tag is required")
+	}
+
+	err := InvalidateTags(a.config.Store, tags...)
+	if errors.Is(err, ErrTagsNotSupported) {
+		return errNotImplemented
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (a *cacheAdmin) clearLevel(c echo.Context) error {
+	var clear func() error
+	level := strings.ToLower(c.Param("level"))
+
+	if twoLevel, ok := StoreAs[*CacheTwoLevelStore](a.config.Store); ok {
+		switch level {
+		case "all":
+			clear = twoLevel.ClearAll
+		case "l1":
+			clear = twoLevel.ClearL1
+		case "l2":
+			clear = twoLevel.ClearL2
+		}
+	} else if level == "all" {
+		clear = func() error { return clearStore(a.config.Store) }
+	}
+	if clear == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown level "+c.Param("level"))
+	}
+
+	err := clear()
+	if errors.Is(err, ErrClearNotSupported) {
+		return errNotImplemented
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// requestedKey returns the key of the url query parameter, the URL of a GET request,
+// or of the key query parameter.
+func requestedKey(c echo.Context) (uint64, error) {
+	if value := c.QueryParam("key"); value != "" {
+		key, err := strconv.ParseUint(value, 36, 64)
+		if err != nil {
+			return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid key")
+		}
+		return key, nil
+	}
+
+	value := c.QueryParam("url")
+	if value == "" {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "url or key is required")
+	}
+	u, err := url.ParseRequestURI(value)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid url")
+	}
+	sortURLParams(u)
+	return generateKey(http.MethodGet, u.String()), nil
+}
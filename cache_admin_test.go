@@ -0,0 +1,230 @@
+package echo_http_cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAdminTestServer returns a server caching /products with the admin API mounted on /admin.
+func newAdminTestServer(config CacheAdminConfig) *echo.Echo {
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:        config.Store,
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/products"},
+		Metrics:      config.Metrics,
+	}))
+	e.GET("/products", func(c echo.Context) error {
+		SetCacheTags(c, "products")
+		return c.String(http.StatusOK, "products "+c.QueryString())
+	})
+	CacheAdminWithConfig(e.Group("/admin"), config)
+	return e
+}
+
+func serve(e *echo.Echo, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func Test_CacheAdmin(t *testing.T) {
+	store := NewCacheMemoryStore()
+	metrics := &CacheMetrics{}
+	e := newAdminTestServer(CacheAdminConfig{Store: store, Metrics: metrics})
+
+	serve(e, http.MethodGet, "/products?b=2&a=1")
+	serve(e, http.MethodGet, "/products?page=2")
+
+	t.Run("stats", func(t *testing.T) {
+		rec := serve(e, http.MethodGet, "/admin/stats")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var stats AdminStats
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+		if assert.NotNil(t, stats.Entries) {
+			assert.Equal(t, 2, *stats.Entries)
+		}
+		if assert.NotNil(t, stats.Evictions) {
+			assert.Equal(t, int64(0), *stats.Evictions)
+		}
+		assert.NotNil(t, stats.Middleware)
+		assert.Nil(t, stats.Store)
+		assert.Nil(t, stats.QueueDepth)
+	})
+
+	t.Run("keys are paginated", func(t *testing.T) {
+		var keys []string
+		cursor := ""
+		for i := 0; i < 2; i++ {
+			rec := serve(e, http.MethodGet, "/admin/keys?limit=1&cursor="+cursor)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var page AdminKeys
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+			assert.Len(t, page.Keys, 1)
+			keys = append(keys, page.Keys...)
+			cursor = page.Next
+		}
+
+		// the second page is the last one
+		assert.Empty(t, cursor)
+		assert.ElementsMatch(t, []string{
+			keyAsString(generateKey(http.MethodGet, "/products?a=1&b=2")),
+			keyAsString(generateKey(http.MethodGet, "/products?page=2")),
+		}, keys)
+
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodGet, "/admin/keys?limit=0").Code)
+	})
+
+	t.Run("entry is looked up by URL or key", func(t *testing.T) {
+		for _, query := range []string{
+			"url=" + url.QueryEscape("/products?b=2&a=1"),
+			"key=" + keyAsString(generateKey(http.MethodGet, "/products?a=1&b=2")),
+		} {
+			rec := serve(e, http.MethodGet, "/admin/entry?"+query)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var entry AdminEntry
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entry))
+			assert.Equal(t, "/products?a=1&b=2", entry.URL)
+			assert.Equal(t, http.StatusOK, entry.StatusCode)
+			assert.Equal(t, len("products a=1&b=2"), entry.Size)
+			assert.Equal(t, 1, entry.Frequency)
+			assert.Equal(t, []string{"products"}, entry.Tags)
+			assert.Equal(t, "text/plain; charset=UTF-8", entry.Header.Get(echo.HeaderContentType))
+		}
+
+		assert.Equal(t, http.StatusNotFound, serve(e, http.MethodGet, "/admin/entry?url=/unknown").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodGet, "/admin/entry").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodGet, "/admin/entry?key=-").Code)
+	})
+
+	t.Run("purge by URL", func(t *testing.T) {
+		rec := serve(e, http.MethodDelete, "/admin/entry?url="+url.QueryEscape("/products?page=2"))
+		assert.JSONEq(t, `{"purged":1}`, rec.Body.String())
+
+		rec = serve(e, http.MethodDelete, "/admin/entry?url="+url.QueryEscape("/products?page=2"))
+		assert.JSONEq(t, `{"purged":0}`, rec.Body.String())
+	})
+
+	t.Run("purge by prefix or pattern", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodDelete, "/admin/prefix").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodDelete, "/admin/pattern").Code)
+
+		rec := serve(e, http.MethodDelete, "/admin/pattern?pattern=/products/*")
+		assert.JSONEq(t, `{"purged":0}`, rec.Body.String())
+
+		rec = serve(e, http.MethodDelete, "/admin/prefix?prefix=/prod")
+		assert.JSONEq(t, `{"purged":1}`, rec.Body.String())
+		assert.Equal(t, 0, store.Size())
+	})
+
+	t.Run("purge by tag", func(t *testing.T) {
+		serve(e, http.MethodGet, "/products")
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodDelete, "/admin/tags").Code)
+		assert.Equal(t, http.StatusNoContent, serve(e, http.MethodDelete, "/admin/tags?tag=products").Code)
+		assert.Equal(t, 0, store.Size())
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		serve(e, http.MethodGet, "/products")
+		assert.Equal(t, http.StatusNotFound, serve(e, http.MethodDelete, "/admin/levels/l1").Code)
+		assert.Equal(t, http.StatusNoContent, serve(e, http.MethodDelete, "/admin/levels/all").Code)
+		assert.Equal(t, 0, store.Size())
+	})
+}
+
+func Test_CacheAdmin_twoLevelStore(t *testing.T) {
+	l1, l2 := NewCacheMemoryStore(), NewCacheMemoryStore()
+	store := NewCacheTwoLevelStore(l1, l2)
+	e := newAdminTestServer(CacheAdminConfig{Store: store})
+	serve(e, http.MethodGet, "/products")
+
+	rec := serve(e, http.MethodGet, "/admin/stats")
+	var stats AdminStats
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	if assert.NotNil(t, stats.Store) {
+		assert.Equal(t, int64(1), stats.Store.TotalMiss)
+		assert.Equal(t, 1, stats.Store.L1Size)
+	}
+	assert.NotNil(t, stats.QueueDepth)
+
+	assert.Equal(t, http.StatusNoContent, serve(e, http.MethodDelete, "/admin/levels/L1").Code)
+	assert.Equal(t, 0, l1.Size())
+	assert.Equal(t, 1, l2.Size())
+	assert.Equal(t, http.StatusNotFound, serve(e, http.MethodDelete, "/admin/levels/l3").Code)
+}
+
+func Test_CacheAdmin_wrappedStore(t *testing.T) {
+	l1, l2 := NewCacheMemoryStore(), NewCacheMemoryStore()
+	store := wrappingStore{NewCacheTwoLevelStore(l1, l2)}
+	e := newAdminTestServer(CacheAdminConfig{Store: store})
+	serve(e, http.MethodGet, "/products")
+
+	// the optional interfaces of the wrapped store are found
+	rec := serve(e, http.MethodGet, "/admin/stats")
+	var stats AdminStats
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.NotNil(t, stats.Store)
+	assert.Nil(t, stats.Entries)
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodGet, "/admin/keys").Code)
+
+	assert.Equal(t, http.StatusNoContent, serve(e, http.MethodDelete, "/admin/levels/l2").Code)
+	assert.Equal(t, 1, l1.Size())
+	assert.Equal(t, 0, l2.Size())
+	assert.Equal(t, http.StatusNoContent, serve(e, http.MethodDelete, "/admin/levels/all").Code)
+	assert.Equal(t, 0, l1.Size())
+}
+
+func Test_CacheAdmin_unclearableLevel(t *testing.T) {
+	l1 := NewCacheMemoryStore()
+	e := newAdminTestServer(CacheAdminConfig{Store: NewCacheTwoLevelStore(l1, untaggedStore{NewCacheMemoryStore()})})
+	serve(e, http.MethodGet, "/products")
+
+	assert.Equal(t, http.StatusNotImplemented, serve(e, http.MethodDelete, "/admin/levels/l2").Code)
+	assert.Equal(t, http.StatusNotImplemented, serve(e, http.MethodDelete, "/admin/levels/all").Code)
+	assert.Equal(t, http.StatusNoContent, serve(e, http.MethodDelete, "/admin/levels/l1").Code)
+}
+
+func Test_CacheAdmin_authorizer(t *testing.T) {
+	e := newAdminTestServer(CacheAdminConfig{
+		Store: NewCacheMemoryStore(),
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/admin/stats"
+		},
+		Authorizer: func(c echo.Context) error {
+			if c.Request().Header.Get(echo.HeaderAuthorization) != "Bearer secret" {
+				return echo.ErrUnauthorized
+			}
+			return nil
+		},
+	})
+
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodGet, "/admin/stats").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(e, http.MethodDelete, "/admin/levels/all").Code)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/levels/all", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func Test_CacheAdmin_unsupportedStore(t *testing.T) {
+	e := newAdminTestServer(CacheAdminConfig{Store: struct{ CacheStore }{NewCacheMemoryStore()}})
+
+	assert.Equal(t, http.StatusNotImplemented, serve(e, http.MethodGet, "/admin/keys").Code)
+	assert.Equal(t, http.StatusNotImplemented, serve(e, http.MethodDelete, "/admin/tags?tag=products").Code)
+	assert.Equal(t, http.StatusNotImplemented, serve(e, http.MethodDelete, "/admin/prefix?prefix=/products").Code)
+	assert.Equal(t, http.StatusNotImplemented, serve(e, http.MethodDelete, "/admin/pattern?pattern=/products").Code)
+	assert.Equal(t, http.StatusNotImplemented, serve(e, http.MethodDelete, "/admin/levels/all").Code)
+	assert.JSONEq(t, `{}`, serve(e, http.MethodGet, "/admin/stats").Body.String())
+}
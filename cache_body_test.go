@@ -0,0 +1,107 @@
+package echo_http_cache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_canonicalizeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "json keys are sorted",
+			contentType: "application/json; charset=utf-8",
+			body:        `{ "b": 1, "a": {"d": 12345678901234567890, "c": [true, null]} }`,
+			want:        `{"a":{"c":[true,null],"d":12345678901234567890},"b":1}`,
+		},
+		{
+			name:        "json suffix",
+			contentType: "application/graphql+json",
+			body:        `{"variables": {}, "query": "{ a }"}`,
+			want:        `{"query":"{ a }","variables":{}}`,
+		},
+		{
+			name:        "invalid json is kept",
+			contentType: "application/json",
+			body:        `{"a":`,
+			want:        `{"a":`,
+		},
+		{
+			name:        "other content types are kept",
+			contentType: "text/plain",
+			body:        `{ "b": 1, "a": 2 }`,
+			want:        `{ "b": 1, "a": 2 }`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(canonicalizeBody(tt.contentType, []byte(tt.body))))
+		})
+	}
+}
+
+func Test_readRequestBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+
+	_, ok := readRequestBody(req, 5)
+	assert.False(t, ok)
+
+	// the body is restored for the handler
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "0123456789", string(body))
+}
+
+func Test_CacheWithConfig_post(t *testing.T) {
+	e := echo.New()
+
+	calls := 0
+	handler := func(c echo.Context) error {
+		calls++
+		body, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusOK, "result "+string(body))
+	}
+
+	mw := CacheWithConfig(CacheConfig{
+		Store:              NewCacheMemoryStore(),
+		Expiration:         5 * time.Second,
+		IncludePOSTPaths:   []string{"/search"},
+		MaxRequestBodySize: 32,
+	})
+
+	tests := []struct {
+		name      string
+		url       string
+		body      string
+		wantBody  string
+		wantCalls int
+	}{
+		{"first request", "/search", `{"q":"a","page":1}`, `result {"q":"a","page":1}`, 1},
+		{"same body with different key order", "/search", `{"page":1, "q":"a"}`, `result {"q":"a","page":1}`, 1},
+		{"different body", "/search", `{"q":"b","page":1}`, `result {"q":"b","page":1}`, 2},
+		{"body larger than the limit", "/search", `{"q":"` + strings.Repeat("a", 32) + `"}`, `result {"q":"` + strings.Repeat("a", 32) + `"}`, 3},
+		{"path not included", "/other", `{"q":"a","page":1}`, `result {"q":"a","page":1}`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://foo.bar"+tt.url, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			_ = mw(handler)(e.NewContext(req, rec))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.wantBody, rec.Body.String())
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// flightGroup coalesces concurrent cache misses for the same key. The first request,
+// the leader, runs the handler while the others wait for its response.
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[uint64]*flightCall
+}
+
+// flightCall is a handler call in flight.
+type flightCall struct {
+	done chan struct{}
+
+	// response is the response of the leader, or nil when it cannot be shared.
+	response *CacheResponse
+
+	// key is the key the response is stored under, which differs from the
+	// key of the call for responses with a Vary header.
+	key uint64
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[uint64]*flightCall)}
+}
+
+// join returns the call in flight for the key. It returns true when the caller is the
+// leader and must call finish once the response is known.
+func (g *flightGroup) join(key uint64) (*flightCall, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	return call, true
+}
+
+// finish publishes the response of the leader to the waiting requests.
+func (g *flightGroup) finish(key uint64, call *flightCall, response *CacheResponse, storedKey uint64) {
+	g.mutex.Lock()
+	delete(g.calls, key)
+	g.mutex.Unlock()
+
+	call.response = response
+	call.key = storedKey
+	close(call.done)
+}
+
+// wait waits for the response of the leader until timeout. It returns false when
+// the timeout expires or the leader has no response to share.
+func (call *flightCall) wait(timeout time.Duration) (*CacheResponse, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-call.done:
+		return call.response, call.response != nil
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+// matches reports whether the response of the leader is the variant selected by the request.
+func (call *flightCall) matches(key uint64, req *http.Request) bool {
+	names := variantHeaders(call.response.Header)
+	return len(names) == 0 || varyKey(key, names, req.Header) == call.key
+}
@@ -0,0 +1,137 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_flightGroup(t *testing.T) {
+	group := newFlightGroup()
+
+	call, leader := group.join(1)
+	assert.True(t, leader)
+
+	follower, leader := group.join(1)
+	assert.False(t, leader)
+	assert.Same(t, call, follower)
+
+	_, ok := follower.wait(10 * time.Millisecond)
+	assert.False(t, ok)
+
+	group.finish(1, call, &CacheResponse{Body: []byte("test")}, 1)
+	response, ok := follower.wait(10 * time.Millisecond)
+	assert.True(t, ok)
+	assert.Equal(t, "test", string(response.Body))
+
+	// a new call starts once the previous one finished
+	_, leader = group.join(1)
+	assert.True(t, leader)
+}
+
+func Test_CacheWithConfig_coalesceRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+	}{
+		{
+			name:      "followers get the response of the leader",
+			status:    http.StatusOK,
+			wantCalls: 1,
+		},
+		{
+			name:      "followers run the handler when the response is not cacheable",
+			status:    http.StatusInternalServerError,
+			wantCalls: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			release := make(chan struct{})
+			metrics := &CacheMetrics{}
+
+			e := echo.New()
+			e.Use(CacheWithConfig(CacheConfig{
+				Store:            NewCacheMemoryStore(),
+				Expiration:       5 * time.Second,
+				IncludePaths:     []string{"/coalesce"},
+				CoalesceRequests: true,
+				Metrics:          metrics,
+			}))
+			e.GET("/coalesce", func(c echo.Context) error {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return c.String(tt.status, "test")
+			})
+
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rec := httptest.NewRecorder()
+					e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/coalesce", nil))
+
+					assert.Equal(t, tt.status, rec.Code)
+					assert.Equal(t, "test", rec.Body.String())
+				}()
+			}
+
+			// let the followers join the leader
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+			stats := metrics.GetStats()
+			assert.Equal(t, int64(5-tt.wantCalls), stats.Coalesced)
+			if stats.Coalesced > 0 {
+				assert.True(t, stats.CoalesceWait > 0)
+			}
+		})
+	}
+}
+
+func Test_CacheWithConfig_coalesceTimeout(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:            NewCacheMemoryStore(),
+		Expiration:       5 * time.Second,
+		IncludePaths:     []string{"/coalesce"},
+		CoalesceRequests: true,
+		CoalesceTimeout:  10 * time.Millisecond,
+	}))
+	e.GET("/coalesce", func(c echo.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+		}
+		return c.String(http.StatusOK, "test")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/coalesce", nil))
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the follower gives up waiting and runs the handler itself
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/coalesce", nil))
+	assert.Equal(t, "test", rec.Body.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	close(release)
+	<-done
+}
@@ -0,0 +1,265 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type (
+	// Codec encodes the cached responses into the bytes kept by the stores. The memory
+	// store evicts entries by their LastAccess and Frequency, which it reads from the
+	// entries encoded by BinaryCodec or JSONCodec only.
+	Codec interface {
+		// Encode returns the bytes of the response.
+		Encode(response CacheResponse) ([]byte, error)
+
+		// Decode returns the response encoded in data.
+		Decode(data []byte) (CacheResponse, error)
+	}
+
+	// BinaryCodec is the default Codec. It encodes responses in a compact versioned binary
+	// format, starting with a magic number and a version byte. It also decodes the JSON
+	// entries of JSONCodec, so that the entries of older deployments keep being served.
+	BinaryCodec struct{}
+
+	// JSONCodec encodes responses in JSON, the format of the versions before BinaryCodec.
+	JSONCodec struct{}
+)
+
+// binaryMagic starts the entries of BinaryCodec. It is not valid JSON, so that both
+// formats can be told apart.
+var binaryMagic = []byte{0xec, 0xac}
+
+// binaryVersion is the version of the BinaryCodec format.
+const binaryVersion byte = 1
+
+// ErrInvalidEntry is returned when a cache entry cannot be decoded, such as an entry
+// written by an unknown version of the format. Such an entry is treated as a miss.
+var ErrInvalidEntry = errors.New("invalid cache entry")
+
+// Encode implements the Codec interface Encode method.
+func (JSONCodec) Encode(response CacheResponse) ([]byte, error) {
+	return json.Marshal(response)
+}
+
+// Decode implements the Codec interface Decode method.
+func (JSONCodec) Decode(data []byte) (CacheResponse, error) {
+	var response CacheResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return CacheResponse{}, fmt.Errorf("%w: %v", ErrInvalidEntry, err)
+	}
+	return response, nil
+}
+
+// Encode implements the Codec interface Encode method.
+func (BinaryCodec) Encode(response CacheResponse) ([]byte, error) {
+	w := binaryWriter{buf: make([]byte, 0, len(response.Body)+256)}
+	w.buf = append(w.buf, binaryMagic...)
+	w.buf = append(w.buf, binaryVersion)
+
+	w.string(response.URL)
+	w.varint(int64(response.StatusCode))
+	w.uvarint(uint64(len(response.Header)))
+	for k, values := range response.Header {
+		w.string(k)
+		w.strings(values)
+	}
+	w.bytes(response.Body)
+	w.time(response.Expiration)
+	w.time(response.LastAccess)
+	w.varint(int64(response.Frequency))
+	w.strings(response.VaryHeaders)
+	w.strings(response.Tags)
+	w.string(response.ContentEncoding)
+	return w.buf, nil
+}
+
+// Decode implements the Codec interface Decode method.
+func (BinaryCodec) Decode(data []byte) (CacheResponse, error) {
+	if !bytes.HasPrefix(data, binaryMagic) {
+		if len(data) > 0 && data[0] == '{' {
+			return JSONCodec{}.Decode(data)
+		}
+		return CacheResponse{}, ErrInvalidEntry
+	}
+	if len(data) <= len(binaryMagic) || data[len(binaryMagic)] != binaryVersion {
+		return CacheResponse{}, fmt.Errorf("%w: unknown version", ErrInvalidEntry)
+	}
+
+	r := binaryReader{buf: data[len(binaryMagic)+1:]}
+	var response CacheResponse
+	response.URL = r.string()
+	response.StatusCode = int(r.varint())
+	if n := r.length(); n > 0 {
+		response.Header = make(http.Header, n)
+		for i := 0; i < n; i++ {
+			k := r.string()
+			response.Header[k] = r.strings()
+		}
+	}
+	response.Body = r.bytes()
+	response.Expiration = r.time()
+	response.LastAccess = r.time()
+	response.Frequency = int(r.varint())
+	response.VaryHeaders = r.strings()
+	response.Tags = r.strings()
+	response.ContentEncoding = r.string()
+
+	if r.err != nil {
+		return CacheResponse{}, fmt.Errorf("%w: %v", ErrInvalidEntry, r.err)
+	}
+	return response, nil
+}
+
+type binaryWriter struct {
+	buf []byte
+}
+
+func (w *binaryWriter) uvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *binaryWriter) varint(v int64) {
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+func (w *binaryWriter) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *binaryWriter) strings(values []string) {
+	w.uvarint(uint64(len(values)))
+	for _, v := range values {
+		w.string(v)
+	}
+}
+
+// time writes the time in unix nanoseconds, or 0 for the zero time.
+func (w *binaryWriter) time(t time.Time) {
+	if t.IsZero() {
+		w.varint(0)
+		return
+	}
+	w.varint(t.UnixNano())
+}
+
+// binaryReader reads the values written by binaryWriter. The first error is kept in err
+// and the following reads return zero values.
+type binaryReader struct {
+	buf []byte
+	err error
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errors.New("truncated varint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errors.New("truncated varint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+// length reads a length, which cannot exceed the remaining bytes.
+func (r *binaryReader) length() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		if r.err == nil {
+			r.err = errors.New("invalid length")
+		}
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) bytes() []byte {
+	n := r.length()
+	if r.err != nil || n == 0 {
+		return nil
+	}
+	b := make([]byte, n)
+	copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *binaryReader) string() string {
+	n := r.length()
+	if r.err != nil {
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+func (r *binaryReader) strings() []string {
+	n := r.length()
+	if r.err != nil || n == 0 {
+		return nil
+	}
+	values := make([]string, n)
+	for i := range values {
+		values[i] = r.string()
+	}
+	return values
+}
+
+func (r *binaryReader) time() time.Time {
+	v := r.varint()
+	if v == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, v)
+}
@@ -0,0 +1,116 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Codec(t *testing.T) {
+	now := time.Now()
+	response := CacheResponse{
+		URL:             "/test?a=1",
+		StatusCode:      http.StatusCreated,
+		Header:          http.Header{"Content-Type": {"text/plain"}, "Set-Cookie": {"a=1", "b=2"}},
+		Body:            []byte("test"),
+		Expiration:      now.Add(time.Minute),
+		LastAccess:      now,
+		Frequency:       3,
+		Tags:            []string{"a", "b"},
+		ContentEncoding: CompressionGzip,
+	}
+
+	for _, codec := range []Codec{BinaryCodec{}, JSONCodec{}} {
+		data, err := codec.Encode(response)
+		assert.NoError(t, err)
+
+		got, err := codec.Decode(data)
+		assert.NoError(t, err)
+		assert.Equal(t, response.URL, got.URL)
+		assert.Equal(t, response.StatusCode, got.StatusCode)
+		assert.Equal(t, response.Header, got.Header)
+		assert.Equal(t, response.Body, got.Body)
+		assert.True(t, response.Expiration.Equal(got.Expiration))
+		assert.True(t, response.LastAccess.Equal(got.LastAccess))
+		assert.Equal(t, response.Frequency, got.Frequency)
+		assert.Equal(t, response.Tags, got.Tags)
+		assert.Equal(t, response.ContentEncoding, got.ContentEncoding)
+	}
+}
+
+func Test_BinaryCodec(t *testing.T) {
+	response := CacheResponse{Body: []byte(strings.Repeat("a", 1000)), Frequency: 1}
+	data, _ := BinaryCodec{}.Encode(response)
+
+	t.Run("smaller than JSON", func(t *testing.T) {
+		assert.Less(t, len(data), len(response.bytes()))
+	})
+
+	t.Run("zero times", func(t *testing.T) {
+		got, err := BinaryCodec{}.Decode(data)
+		assert.NoError(t, err)
+		assert.True(t, got.Expiration.IsZero())
+	})
+
+	t.Run("decodes JSON entries", func(t *testing.T) {
+		got, err := BinaryCodec{}.Decode(response.bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, response.Body, got.Body)
+	})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"unknown format", []byte("test")},
+		{"unknown version", append(append([]byte{}, binaryMagic...), binaryVersion+1)},
+		{"truncated", data[:len(data)/2]},
+		{"invalid JSON", []byte("{")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BinaryCodec{}.Decode(tt.data)
+			assert.ErrorIs(t, err, ErrInvalidEntry)
+		})
+	}
+}
+
+func Test_CacheWithConfig_codec(t *testing.T) {
+	store := NewCacheMemoryStore()
+	calls := 0
+	handler := func(c echo.Context) error {
+		calls++
+		return c.String(http.StatusOK, "test")
+	}
+	request := func(codec Codec) {
+		mw := CacheWithConfig(CacheConfig{
+			Store:        store,
+			Expiration:   5 * time.Second,
+			IncludePaths: []string{"/"},
+			Codec:        codec,
+		})
+		rec := httptest.NewRecorder()
+		_ = mw(handler)(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+		assert.Equal(t, "test", rec.Body.String())
+	}
+
+	// entries of an older deployment are read
+	request(JSONCodec{})
+	request(nil)
+	assert.Equal(t, 1, calls)
+
+	// invalid entries are discarded
+	store.Set(generateKey(http.MethodGet, "/"), []byte{0xec, 0xac, 0xff}, time.Now().Add(time.Minute))
+	request(nil)
+	assert.Equal(t, 2, calls)
+
+	cached, _ := store.Get(generateKey(http.MethodGet, "/"))
+	_, err := BinaryCodec{}.Decode(cached)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,189 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// CompressionGzip stores the response bodies compressed with gzip.
+	CompressionGzip = "gzip"
+
+	// CompressionZstd stores the response bodies compressed with zstd.
+	CompressionZstd = "zstd"
+)
+
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return encoder
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		decoder, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		return decoder
+	})
+)
+
+// compressResponse compresses the body of the response with the configured compression
+// when it is large enough, has a compressible content type and is not encoded already.
+func (c *CacheConfig) compressResponse(response *CacheResponse) {
+	if c.Compression == "" || len(response.Body) < c.CompressionMinSize {
+		return
+	}
+	if response.Header.Get(echo.HeaderContentEncoding) != "" || !isCompressible(response.Header.Get(echo.HeaderContentType)) {
+		return
+	}
+
+	body, err := compressBody(c.Compression, response.Body)
+	if err != nil || len(body) >= len(response.Body) {
+		return
+	}
+	response.Body = body
+	response.ContentEncoding = c.Compression
+	response.Header.Del(echo.HeaderContentLength)
+}
+
+// isCompressible reports whether responses of the content type are worth compressing.
+func isCompressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+func compressBody(encoding string, body []byte) ([]byte, error) {
+	switch encoding {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		return zstdEncoder().EncodeAll(body, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported compression %q", encoding)
+}
+
+func decompressBody(encoding string, body []byte) ([]byte, error) {
+	switch encoding {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CompressionZstd:
+		return zstdDecoder().DecodeAll(body, nil)
+	}
+	return nil, fmt.Errorf("unsupported compression %q", encoding)
+}
+
+// negotiateEncoding returns the content encoding the cached response is sent with. The
+// compressed body is sent as is when the request accepts its encoding and no compression
+// middleware, such as echo's Gzip which sets Vary: Accept-Encoding, is wrapping the response.
+// Otherwise it is sent decompressed, without encoding.
+func negotiateEncoding(c echo.Context, response CacheResponse) string {
+	if response.ContentEncoding == "" {
+		return ""
+	}
+
+	compressed := hasHeaderToken(c.Response().Header(), echo.HeaderVary, echo.HeaderAcceptEncoding)
+	if !compressed && acceptsEncoding(c.Request(), response.ContentEncoding) {
+		return response.ContentEncoding
+	}
+	return ""
+}
+
+// encodedBody returns the body of the cached response to send with the negotiated encoding.
+func encodedBody(response CacheResponse, encoding string) ([]byte, error) {
+	if response.ContentEncoding == "" || encoding != "" {
+		return response.Body, nil
+	}
+	return decompressBody(response.ContentEncoding, response.Body)
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header of the request allows the encoding.
+func acceptsEncoding(req *http.Request, encoding string) bool {
+	accepted := false
+	for _, value := range req.Header.Values(echo.HeaderAcceptEncoding) {
+		for _, part := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != encoding && name != "*" {
+				continue
+			}
+
+			q := 1.0
+			if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+			if name == encoding {
+				return q > 0
+			}
+			accepted = q > 0
+		}
+	}
+	return accepted
+}
+
+// hasHeaderToken reports whether the comma separated values of the header contain the token.
+func hasHeaderToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package echo_http_cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_acceptsEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+		want           bool
+	}{
+		{"", CompressionGzip, false},
+		{"gzip, deflate, br", CompressionGzip, true},
+		{"deflate, br", CompressionGzip, false},
+		{"GZIP;q=0.5", CompressionGzip, true},
+		{"gzip;q=0", CompressionGzip, false},
+		{"*", CompressionZstd, true},
+		{"*, zstd;q=0", CompressionZstd, false},
+		{"zstd;q=0, *", CompressionZstd, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding+" "+tt.encoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAcceptEncoding, tt.acceptEncoding)
+			assert.Equal(t, tt.want, acceptsEncoding(req, tt.encoding))
+		})
+	}
+}
+
+func Test_compressBody(t *testing.T) {
+	body := []byte(strings.Repeat("compressible ", 100))
+	for _, encoding := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(encoding, func(t *testing.T) {
+			compressed, err := compressBody(encoding, body)
+			assert.NoError(t, err)
+			assert.Less(t, len(compressed), len(body))
+
+			decompressed, err := decompressBody(encoding, compressed)
+			assert.NoError(t, err)
+			assert.Equal(t, body, decompressed)
+		})
+	}
+
+	_, err := compressBody("br", body)
+	assert.Error(t, err)
+}
+
+func Test_CacheWithConfig_compression(t *testing.T) {
+	body := `{"items":"` + strings.Repeat("item ", 500) + `"}`
+
+	tests := []struct {
+		name           string
+		compression    string
+		gzip           bool
+		method         string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{"gzip client", CompressionGzip, false, http.MethodGet, "gzip, deflate", CompressionGzip},
+		{"identity client", CompressionGzip, false, http.MethodGet, "", ""},
+		{"zstd client", CompressionZstd, false, http.MethodGet, "zstd", CompressionZstd},
+		{"HEAD request", CompressionGzip, false, http.MethodHead, "gzip", CompressionGzip},
+		{"echo gzip middleware", CompressionZstd, true, http.MethodGet, "gzip, zstd", CompressionGzip},
+		{"echo gzip middleware with the same encoding", CompressionGzip, true, http.MethodGet, "gzip", CompressionGzip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewCacheMemoryStore()
+			e := echo.New()
+			if tt.gzip {
+				e.Use(middleware.Gzip())
+			}
+			e.Use(CacheWithConfig(CacheConfig{
+				Store:        store,
+				Expiration:   5 * time.Second,
+				IncludePaths: []string{"/items"},
+				Compression:  tt.compression,
+			}))
+			e.GET("/items", func(c echo.Context) error {
+				return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, []byte(body))
+			})
+
+			// the first request stores the response
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+			cached, ok := store.Get(generateKey(http.MethodGet, "/items"))
+			assert.True(t, ok)
+			assert.Equal(t, tt.compression, toCacheResponse(cached).ContentEncoding)
+			assert.Less(t, len(toCacheResponse(cached).Body), len(body))
+
+			req := httptest.NewRequest(tt.method, "/items", nil)
+			req.Header.Set(echo.HeaderAcceptEncoding, tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.wantEncoding, rec.Header().Get(echo.HeaderContentEncoding))
+			assert.Equal(t, []string{echo.HeaderAcceptEncoding}, rec.Header().Values(echo.HeaderVary))
+			if tt.method == http.MethodHead {
+				assert.Equal(t, 0, rec.Body.Len())
+				return
+			}
+
+			// the body is compressed once
+			decoded := rec.Body.Bytes()
+			switch tt.wantEncoding {
+			case CompressionGzip:
+				r, err := gzip.NewReader(bytes.NewReader(decoded))
+				assert.NoError(t, err)
+				decoded, _ = io.ReadAll(r)
+			case CompressionZstd:
+				decoded, _ = decompressBody(CompressionZstd, decoded)
+			}
+			assert.Equal(t, body, string(decoded))
+		})
+	}
+}
+
+func Test_CacheWithConfig_compressionETag(t *testing.T) {
+	body := strings.Repeat("item ", 500)
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:        NewCacheMemoryStore(),
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/items"},
+		Compression:  CompressionGzip,
+	}))
+	e.GET("/items", func(c echo.Context) error {
+		return c.String(http.StatusOK, body)
+	})
+
+	get := func(acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	get("", "")
+
+	// the encoded and the decoded bodies have different strong validators
+	etag := generateETag([]byte(body))
+	gzipETag := strings.TrimSuffix(etag, `"`) + `-gzip"`
+	assert.Equal(t, etag, get("", "").Header().Get("ETag"))
+	assert.Equal(t, gzipETag, get("gzip", "").Header().Get("ETag"))
+
+	rec := get("gzip", gzipETag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, gzipETag, rec.Header().Get("ETag"))
+	assert.Equal(t, http.StatusOK, get("gzip", etag).Code)
+	assert.Equal(t, http.StatusOK, get("", gzipETag).Code)
+}
+
+func Test_CacheWithConfig_compressionSkipped(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        string
+	}{
+		{"small body", echo.MIMEApplicationJSON, "", `{"a":1}`},
+		{"not compressible", "image/png", "", strings.Repeat("a", 2048)},
+		{"encoded by the handler", echo.MIMEApplicationJSON, "br", strings.Repeat("a", 2048)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewCacheMemoryStore()
+			mw := CacheWithConfig(CacheConfig{
+				Store:        store,
+				Expiration:   5 * time.Second,
+				IncludePaths: []string{"/items"},
+				Compression:  CompressionGzip,
+			})
+			handler := func(c echo.Context) error {
+				if tt.encoding != "" {
+					c.Response().Header().Set(echo.HeaderContentEncoding, tt.encoding)
+				}
+				return c.Blob(http.StatusOK, tt.contentType, []byte(tt.body))
+			}
+
+			_ = mw(handler)(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/items", nil), httptest.NewRecorder()))
+
+			cached, ok := store.Get(generateKey(http.MethodGet, "/items"))
+			assert.True(t, ok)
+			assert.Equal(t, "", toCacheResponse(cached).ContentEncoding)
+			assert.Equal(t, tt.body, string(toCacheResponse(cached).Body))
+		})
+	}
+}
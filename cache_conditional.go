@@ -0,0 +1,129 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// notModifiedHeaders are the headers sent with a 304 Not Modified response (RFC 9110 15.4.5)
+var notModifiedHeaders = []string{
+	"Cache-Control",
+	"Content-Location",
+	"Date",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+	"Vary",
+}
+
+// setValidators keeps the ETag and Last-Modified headers set by the handler,
+// or generates them when they are missing.
+func setValidators(header http.Header, body []byte, now time.Time) {
+	if header.Get("ETag") == "" {
+		header.Set("ETag", generateETag(body))
+	}
+	if header.Get("Last-Modified") == "" {
+		header.Set("Last-Modified", now.UTC().Format(http.TimeFormat))
+	}
+}
+
+// generateETag returns a strong ETag computed from the response body.
+func generateETag(body []byte) string {
+	hash := fnv.New128a()
+	hash.Write(body)
+
+	return fmt.Sprintf(`"%x"`, hash.Sum(nil))
+}
+
+// encodedETag returns the ETag of the representation sent with the content encoding. A strong
+// ETag gets the encoding as suffix, since the encoded and the decoded bodies are different
+// representations which cannot share a strong validator (RFC 9110 8.8.3).
+func encodedETag(etag, encoding string) string {
+	if encoding == "" || len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + encoding + `"`
+}
+
+// isNotModified evaluates If-None-Match and If-Modified-Since against the cached response.
+// If-None-Match takes precedence over If-Modified-Since when both are present.
+func isNotModified(req *http.Request, response CacheResponse) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if response.statusCode() != http.StatusOK {
+		return false
+	}
+
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, response.Header.Get("ETag"))
+	}
+
+	if ifModifiedSince := req.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		lastModified, err := http.ParseTime(response.Header.Get("Last-Modified"))
+		if err != nil {
+			return false
+		}
+		return !lastModified.After(since)
+	}
+	return false
+}
+
+// etagMatches reports whether the If-None-Match list matches the ETag using weak comparison.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified writes a 304 Not Modified response with the validator headers of the cached response.
+func writeNotModified(w http.ResponseWriter, response CacheResponse) {
+	for _, k := range notModifiedHeaders {
+		if v := response.Header.Values(k); len(v) > 0 {
+			w.Header().Set(k, strings.Join(v, ","))
+		}
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -0,0 +1,138 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CacheWithConfig_conditionalRequest(t *testing.T) {
+	e := echo.New()
+
+	lastModified := time.Date(2023, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	type args struct {
+		handlerETag string
+		reqHeader   map[string]string
+	}
+
+	type wants struct {
+		code int
+		etag string
+	}
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "generated etag matches",
+			args: args{
+				reqHeader: map[string]string{"If-None-Match": generateETag([]byte("test"))},
+			},
+			wants: wants{
+				code: http.StatusNotModified,
+				etag: generateETag([]byte("test")),
+			},
+		},
+		{
+			name: "handler etag is kept",
+			args: args{
+				handlerETag: `"v1"`,
+				reqHeader:   map[string]string{"If-None-Match": `W/"v0", W/"v1"`},
+			},
+			wants: wants{
+				code: http.StatusNotModified,
+				etag: `"v1"`,
+			},
+		},
+		{
+			name: "etag does not match",
+			args: args{
+				handlerETag: `"v1"`,
+				reqHeader:   map[string]string{"If-None-Match": `"v2"`},
+			},
+			wants: wants{
+				code: http.StatusOK,
+				etag: `"v1"`,
+			},
+		},
+		{
+			name: "not modified since",
+			args: args{
+				reqHeader: map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			},
+			wants: wants{
+				code: http.StatusNotModified,
+				etag: generateETag([]byte("test")),
+			},
+		},
+		{
+			name: "modified since",
+			args: args{
+				reqHeader: map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)},
+			},
+			wants: wants{
+				code: http.StatusOK,
+				etag: generateETag([]byte("test")),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := func(c echo.Context) error {
+				if tt.args.handlerETag != "" {
+					c.Response().Header().Set("ETag", tt.args.handlerETag)
+				}
+				c.Response().Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+				return c.String(http.StatusOK, "test")
+			}
+
+			mw := CacheWithConfig(CacheConfig{
+				Store:        NewCacheMemoryStore(),
+				Expiration:   5 * time.Second,
+				IncludePaths: []string{"foo.bar"},
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "http://foo.bar/conditional", nil)
+			rec := httptest.NewRecorder()
+			_ = mw(handler)(e.NewContext(req, rec))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			req = httptest.NewRequest(http.MethodGet, "http://foo.bar/conditional", nil)
+			for k, v := range tt.args.reqHeader {
+				req.Header.Set(k, v)
+			}
+			rec = httptest.NewRecorder()
+			_ = mw(handler)(e.NewContext(req, rec))
+
+			assert.Equal(t, tt.wants.code, rec.Code)
+			assert.Equal(t, tt.wants.etag, rec.Header().Get("ETag"))
+			assert.Equal(t, lastModified.Format(http.TimeFormat), rec.Header().Get("Last-Modified"))
+			if tt.wants.code == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+			} else {
+				assert.Equal(t, "test", rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_encodedETag(t *testing.T) {
+	assert.Equal(t, `"a-gzip"`, encodedETag(`"a"`, CompressionGzip))
+	assert.Equal(t, `"a"`, encodedETag(`"a"`, ""))
+	assert.Equal(t, `W/"a"`, encodedETag(`W/"a"`, CompressionZstd))
+}
+
+func Test_etagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a"`, `"a"`))
+	assert.True(t, etagMatches(`"b", "a"`, `"a"`))
+	assert.True(t, etagMatches(`W/"a"`, `"a"`))
+	assert.True(t, etagMatches(`*`, `"a"`))
+	assert.False(t, etagMatches(`"b"`, `"a"`))
+	assert.False(t, etagMatches(`"a"`, ``))
+}
@@ -0,0 +1,161 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheControl holds parsed Cache-Control directives. Directive names are lower-cased
+// and directives without an argument have an empty value.
+type cacheControl map[string]string
+
+// parseCacheControl parses the values of one or more Cache-Control headers.
+func parseCacheControl(values []string) cacheControl {
+	cc := cacheControl{}
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+
+			name, arg, _ := strings.Cut(directive, "=")
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+// requestCacheControl returns the request directives. Pragma: no-cache is honored
+// when no Cache-Control header is present (RFC 9111 5.4).
+func requestCacheControl(req *http.Request) cacheControl {
+	values := req.Header.Values("Cache-Control")
+	if len(values) == 0 && strings.Contains(strings.ToLower(req.Header.Get("Pragma")), "no-cache") {
+		return cacheControl{"no-cache": ""}
+	}
+	return parseCacheControl(values)
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// duration returns the delta-seconds argument of a directive.
+func (cc cacheControl) duration(directive string) (time.Duration, bool) {
+	arg, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// acceptsCached reports whether the request directives allow the cached response
+// to be served at now.
+func (cc cacheControl) acceptsCached(response CacheResponse, now time.Time) bool {
+	if cc.has("no-cache") {
+		return false
+	}
+
+	if maxAge, ok := cc.duration("max-age"); ok {
+		if storedAt := response.storedAt(); !storedAt.IsZero() && now.Sub(storedAt) > maxAge {
+			return false
+		}
+	}
+
+	if minFresh, ok := cc.duration("min-fresh"); ok {
+		if response.Expiration.Sub(now) < minFresh {
+			return false
+		}
+	}
+	return true
+}
+
+// responseExpiration derives the expiration of a response from its Cache-Control and
+// Expires headers (RFC 9111 4.2.1). The configured expiration is used when the response
+// does not define its freshness and caps it otherwise. It returns false when the
+// response must not be stored.
+func responseExpiration(req *http.Request, header http.Header, now, expiration time.Time) (time.Time, bool) {
+	cc := parseCacheControl(header.Values("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") || cc.has("no-cache") {
+		return time.Time{}, false
+	}
+
+	// a shared cache must not reuse responses to authenticated requests unless allowed explicitly (RFC 9111 3.5)
+	if req.Header.Get("Authorization") != "" &&
+		!cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+		return time.Time{}, false
+	}
+
+	var freshness time.Duration
+	var ok bool
+	if freshness, ok = cc.duration("s-maxage"); !ok {
+		freshness, ok = cc.duration("max-age")
+	}
+	if !ok {
+		if expires := header.Get("Expires"); expires != "" {
+			expiresAt, err := http.ParseTime(expires)
+			if err != nil {
+				// an invalid Expires value represents a time in the past
+				return time.Time{}, false
+			}
+
+			date := now
+			if d, err := http.ParseTime(header.Get("Date")); err == nil {
+				date = d
+			}
+			freshness, ok = expiresAt.Sub(date), true
+		}
+	}
+
+	if !ok {
+		return expiration, true
+	}
+	if freshness <= 0 {
+		return time.Time{}, false
+	}
+	if derived := now.Add(freshness); derived.Before(expiration) {
+		return derived, true
+	}
+	return expiration, true
+}
+
+// storedAt returns the time the response was generated, taken from its Date header.
+func (r CacheResponse) storedAt() time.Time {
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}
+	}
+	return date
+}
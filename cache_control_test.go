@@ -0,0 +1,213 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseCacheControl(t *testing.T) {
+	cc := parseCacheControl([]string{`public, Max-Age=30`, `s-maxage="60", no-transform`})
+
+	assert.True(t, cc.has("public"))
+	assert.True(t, cc.has("no-transform"))
+	assert.False(t, cc.has("private"))
+
+	maxAge, ok := cc.duration("max-age")
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, maxAge)
+
+	sMaxAge, ok := cc.duration("s-maxage")
+	assert.True(t, ok)
+	assert.Equal(t, 60*time.Second, sMaxAge)
+
+	_, ok = cc.duration("public")
+	assert.False(t, ok)
+}
+
+func Test_responseExpiration(t *testing.T) {
+	now := time.Now()
+	expiration := now.Add(time.Minute)
+
+	tests := []struct {
+		name          string
+		reqHeader     http.Header
+		header        http.Header
+		want          time.Time
+		wantStoreable bool
+	}{
+		{
+			name:          "no directives uses configured expiration",
+			header:        http.Header{},
+			want:          expiration,
+			wantStoreable: true,
+		},
+		{
+			name:          "max-age",
+			header:        http.Header{"Cache-Control": {"max-age=30"}},
+			want:          now.Add(30 * time.Second),
+			wantStoreable: true,
+		},
+		{
+			name:          "s-maxage has priority over max-age",
+			header:        http.Header{"Cache-Control": {"max-age=30, s-maxage=10"}},
+			want:          now.Add(10 * time.Second),
+			wantStoreable: true,
+		},
+		{
+			name:          "max-age is capped by configured expiration",
+			header:        http.Header{"Cache-Control": {"max-age=3600"}},
+			want:          expiration,
+			wantStoreable: true,
+		},
+		{
+			name: "expires",
+			header: http.Header{
+				"Date":    {now.UTC().Format(http.TimeFormat)},
+				"Expires": {now.Add(20 * time.Second).UTC().Format(http.TimeFormat)},
+			},
+			want:          now.Add(20 * time.Second).Truncate(time.Second),
+			wantStoreable: true,
+		},
+		{
+			name:   "no-store",
+			header: http.Header{"Cache-Control": {"no-store"}},
+		},
+		{
+			name:   "private",
+			header: http.Header{"Cache-Control": {"private, max-age=30"}},
+		},
+		{
+			name:   "max-age=0",
+			header: http.Header{"Cache-Control": {"max-age=0"}},
+		},
+		{
+			name:   "invalid expires",
+			header: http.Header{"Expires": {"0"}},
+		},
+		{
+			name:      "authorization without public",
+			reqHeader: http.Header{"Authorization": {"Bearer token"}},
+			header:    http.Header{"Cache-Control": {"max-age=30"}},
+		},
+		{
+			name:          "authorization with public",
+			reqHeader:     http.Header{"Authorization": {"Bearer token"}},
+			header:        http.Header{"Cache-Control": {"public, max-age=30"}},
+			want:          now.Add(30 * time.Second),
+			wantStoreable: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.reqHeader {
+				req.Header[k] = v
+			}
+
+			got, storable := responseExpiration(req, tt.header, now, expiration)
+			assert.Equal(t, tt.wantStoreable, storable)
+			if tt.wantStoreable {
+				assert.WithinDuration(t, tt.want, got, time.Second)
+			}
+		})
+	}
+}
+
+func Test_CacheWithConfig_respectCacheControl(t *testing.T) {
+	e := echo.New()
+
+	type args struct {
+		resCacheControl string
+		reqHeader       http.Header
+	}
+
+	type wants struct {
+		code  int
+		calls int
+	}
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name:  "cached response is served",
+			wants: wants{code: http.StatusOK, calls: 1},
+		},
+		{
+			name:  "no-store response is not cached",
+			args:  args{resCacheControl: "no-store"},
+			wants: wants{code: http.StatusOK, calls: 2},
+		},
+		{
+			name:  "request no-cache goes to the handler",
+			args:  args{reqHeader: http.Header{"Cache-Control": {"no-cache"}}},
+			wants: wants{code: http.StatusOK, calls: 2},
+		},
+		{
+			name:  "request pragma no-cache goes to the handler",
+			args:  args{reqHeader: http.Header{"Pragma": {"no-cache"}}},
+			wants: wants{code: http.StatusOK, calls: 2},
+		},
+		{
+			name:  "request min-fresh longer than freshness goes to the handler",
+			args:  args{reqHeader: http.Header{"Cache-Control": {"min-fresh=60"}}},
+			wants: wants{code: http.StatusOK, calls: 2},
+		},
+		{
+			name:  "request max-age within age is served from the cache",
+			args:  args{reqHeader: http.Header{"Cache-Control": {"max-age=60"}}},
+			wants: wants{code: http.StatusOK, calls: 1},
+		},
+		{
+			name:  "only-if-cached with a cached response",
+			args:  args{reqHeader: http.Header{"Cache-Control": {"only-if-cached"}}},
+			wants: wants{code: http.StatusOK, calls: 1},
+		},
+		{
+			name: "only-if-cached without a cached response",
+			args: args{
+				resCacheControl: "private",
+				reqHeader:       http.Header{"Cache-Control": {"only-if-cached"}},
+			},
+			wants: wants{code: http.StatusGatewayTimeout, calls: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := func(c echo.Context) error {
+				calls++
+				if tt.args.resCacheControl != "" {
+					c.Response().Header().Set("Cache-Control", tt.args.resCacheControl)
+				}
+				return c.String(http.StatusOK, "test")
+			}
+
+			mw := CacheWithConfig(CacheConfig{
+				Store:               NewCacheMemoryStore(),
+				Expiration:          5 * time.Second,
+				IncludePaths:        []string{"foo.bar"},
+				RespectCacheControl: true,
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "http://foo.bar/cache-control", nil)
+			_ = mw(handler)(e.NewContext(req, httptest.NewRecorder()))
+
+			req = httptest.NewRequest(http.MethodGet, "http://foo.bar/cache-control", nil)
+			for k, v := range tt.args.reqHeader {
+				req.Header[k] = v
+			}
+			rec := httptest.NewRecorder()
+			_ = mw(handler)(e.NewContext(req, rec))
+
+			assert.Equal(t, tt.wants.code, rec.Code)
+			assert.Equal(t, tt.wants.calls, calls)
+		})
+	}
+}
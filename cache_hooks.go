@@ -0,0 +1,124 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	// CacheHooks are called with the decisions of the middleware, to log, trace or count
+	// them per route. They run on the request goroutine and nil hooks are skipped.
+	CacheHooks struct {
+		// OnHit is called when a response is served from the cache, including stale responses.
+		OnHit func(c echo.Context, event CacheEvent)
+
+		// OnMiss is called when no fresh response is cached and the handler is called.
+		OnMiss func(c echo.Context, event CacheEvent)
+
+		// OnStore is called when the response of the handler is stored.
+		OnStore func(c echo.Context, event CacheEvent)
+
+		// OnBypass is called when a request is not looked up in the cache, or when its
+		// response is not stored because it is not cacheable.
+		OnBypass func(c echo.Context, event CacheEvent)
+
+		// OnError is called when the store fails, in addition to CacheConfig.ErrorHandler.
+		OnError func(c echo.Context, event CacheEvent)
+	}
+
+	// CacheEvent describes a decision of the middleware.
+	CacheEvent struct {
+		// Key is the key of the response, 0 when none is computed.
+		Key uint64
+
+		// Rule is the rule matching the request, nil for the requests invalidating the cache.
+		Rule *CacheRule
+
+		// Reason qualifies the decision:
+		//   - OnHit: "stale-while-revalidate" or "stale-if-error" for stale responses
+		//   - OnMiss: "uri-miss", "vary-miss", "stale", "request" when the cache is not
+		//     looked up, or "collapsed" when the response of a coalesced request is shared
+		//   - OnBypass: "excluded", "key", "request-body", "head", "uncacheable-body",
+		//     "status", "no-store" or "should-cache"
+		Reason string
+
+		// Level is the store level a hit comes from, if known.
+		Level string
+
+		// Entry describes the cached response of hits and stored responses.
+		Entry *CacheEntryInfo
+
+		// Err is the store error of OnError.
+		Err error
+	}
+
+	// CacheEntryInfo describes a cached response without its body.
+	CacheEntryInfo struct {
+		URL        string
+		StatusCode int
+
+		// Size is the size of the stored body, compressed or not.
+		Size int
+
+		StoredAt        time.Time
+		Expiration      time.Time
+		Tags            []string
+		ContentEncoding string
+	}
+)
+
+// entryInfo returns the metadata of the cached response.
+func entryInfo(r CacheResponse) *CacheEntryInfo {
+	return &CacheEntryInfo{
+		URL:             r.URL,
+		StatusCode:      r.statusCode(),
+		Size:            len(r.Body),
+		StoredAt:        r.storedAt(),
+		Expiration:      r.Expiration,
+		Tags:            r.Tags,
+		ContentEncoding: r.ContentEncoding,
+	}
+}
+
+// notify calls the hook with the event when it is set.
+func notify(hook func(c echo.Context, event CacheEvent), c echo.Context, event CacheEvent) {
+	if hook != nil {
+		hook(c, event)
+	}
+}
+
+// bypass notifies OnBypass of a request or response not cached for the reason.
+func (m *cacheMiddleware) bypass(c echo.Context, rule *CacheRule, key uint64, reason string) {
+	notify(m.config.Hooks.OnBypass, c, CacheEvent{Key: key, Rule: rule, Reason: reason})
+}
+
+// storeError reports the error of the store to the ErrorHandler and OnError.
+func (m *cacheMiddleware) storeError(c echo.Context, rule *CacheRule, err *StoreError) {
+	m.config.ErrorHandler(c, err)
+	notify(m.config.Hooks.OnError, c, CacheEvent{Key: err.Key, Rule: rule, Err: err})
+}
@@ -0,0 +1,136 @@
+package echo_http_cache
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// hookRecorder records the hooks called, as "hook:reason".
+type hookRecorder struct {
+	calls  []string
+	events []CacheEvent
+}
+
+func (r *hookRecorder) hooks() CacheHooks {
+	record := func(name string) func(c echo.Context, event CacheEvent) {
+		return func(c echo.Context, event CacheEvent) {
+			r.calls = append(r.calls, name+":"+event.Reason)
+			r.events = append(r.events, event)
+		}
+	}
+	return CacheHooks{
+		OnHit:    record("hit"),
+		OnMiss:   record("miss"),
+		OnStore:  record("store"),
+		OnBypass: record("bypass"),
+		OnError:  record("error"),
+	}
+}
+
+func Test_CacheWithConfig_hooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		store     CacheStore
+		path      string
+		status    int
+		wantCalls []string
+	}{
+		{
+			name:      "miss, store then hit",
+			store:     NewCacheMemoryStore(),
+			path:      "/test",
+			status:    http.StatusOK,
+			wantCalls: []string{"miss:uri-miss", "store:", "hit:"},
+		},
+		{
+			name:      "excluded path",
+			store:     NewCacheMemoryStore(),
+			path:      "/excluded",
+			status:    http.StatusOK,
+			wantCalls: []string{"bypass:excluded", "bypass:excluded"},
+		},
+		{
+			name:      "uncacheable status code",
+			store:     NewCacheMemoryStore(),
+			path:      "/test",
+			status:    http.StatusInternalServerError,
+			wantCalls: []string{"miss:uri-miss", "bypass:status", "miss:uri-miss", "bypass:status"},
+		},
+		{
+			name:      "store errors",
+			store:     FromCacheStoreV2(failingStore{errors.New("connection refused")}, nil),
+			path:      "/test",
+			status:    http.StatusOK,
+			wantCalls: []string{"error:", "miss:uri-miss", "error:", "error:", "miss:uri-miss", "error:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &hookRecorder{}
+
+			e := echo.New()
+			e.Use(CacheWithConfig(CacheConfig{
+				Store:        tt.store,
+				Expiration:   5 * time.Second,
+				IncludePaths: []string{"/test", "/excluded"},
+				ExcludePaths: []string{"/excluded"},
+				ErrorHandler: func(echo.Context, error) {},
+				Hooks:        recorder.hooks(),
+			}))
+			e.GET(tt.path, func(c echo.Context) error {
+				return c.String(tt.status, "test")
+			})
+
+			for i := 0; i < 2; i++ {
+				e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			}
+			assert.Equal(t, tt.wantCalls, recorder.calls)
+
+			for _, event := range recorder.events {
+				assert.NotNil(t, event.Rule)
+			}
+		})
+	}
+}
+
+func Test_CacheWithConfig_hookEvents(t *testing.T) {
+	recorder := &hookRecorder{}
+
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:        NewCacheMemoryStore(),
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/test"},
+		Hooks:        recorder.hooks(),
+	}))
+	e.GET("/test", func(c echo.Context) error {
+		SetCacheTags(c, "product:42")
+		return c.String(http.StatusOK, "test")
+	})
+
+	for i := 0; i < 2; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	}
+
+	key := generateKey(http.MethodGet, "/test")
+	if assert.Len(t, recorder.events, 3) {
+		stored, hit := recorder.events[1], recorder.events[2]
+		assert.Equal(t, key, stored.Key)
+		assert.Equal(t, key, hit.Key)
+		assert.Same(t, stored.Rule, hit.Rule)
+
+		for _, entry := range []*CacheEntryInfo{stored.Entry, hit.Entry} {
+			assert.Equal(t, "/test", entry.URL)
+			assert.Equal(t, http.StatusOK, entry.StatusCode)
+			assert.Equal(t, len("test"), entry.Size)
+			assert.Equal(t, []string{"product:42"}, entry.Tags)
+			assert.False(t, entry.StoredAt.IsZero())
+		}
+	}
+}
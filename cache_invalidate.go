@@ -0,0 +1,122 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// pathTagPrefix prefixes the tag added to the responses cached when
+// InvalidateOnUnsafeMethods is set, so that every query string variant
+// of a path can be invalidated at once.
+const pathTagPrefix = "path:"
+
+// pathTag returns the tag of the responses cached for the path.
+func pathTag(path string) string {
+	return pathTagPrefix + path
+}
+
+// isUnsafeMethod reports whether the method can change the state of the server.
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// handleUnsafe calls the handler of a request which is not cached and, when it is an unsafe
+// request that succeeded, invalidates the cached GET responses of its path and of the
+// paths mapped to it in InvalidatePaths. The responses are found through the tag index
+// of the store, so the cost of a write does not grow with the size of the cache.
+func (m *cacheMiddleware) handleUnsafe(c echo.Context, next echo.HandlerFunc) error {
+	if !m.config.InvalidateOnUnsafeMethods || !isUnsafeMethod(c.Request().Method) {
+		return next(c)
+	}
+
+	if err := next(c); err != nil {
+		return err
+	}
+
+	if status := c.Response().Status; status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return nil
+	}
+
+	paths := m.config.invalidatedPaths(c)
+	tags := make([]string, len(paths))
+	for i, path := range paths {
+		tags[i] = pathTag(path)
+	}
+
+	err := InvalidateTags(m.config.Store, tags...)
+	if err == ErrTagsNotSupported {
+		// without tags, only the responses cached without a query string are released
+		for _, path := range paths {
+			key := generateKey(http.MethodGet, path)
+			if err := m.store.Delete(c.Request().Context(), key); err != nil {
+				m.storeError(c, nil, &StoreError{Op: "delete", Key: key, Err: err})
+			}
+		}
+	} else if err != nil {
+		m.storeError(c, nil, &StoreError{Op: "invalidate", Err: err})
+	}
+	return nil
+}
+
+// invalidatedPaths returns the request path followed by the paths mapped to the request
+// path or route in InvalidatePaths, with their route parameters replaced by the values
+// of the request. A trailing "?*" is accepted, every query string variant being invalidated anyway.
+func (config CacheConfig) invalidatedPaths(c echo.Context) []string {
+	paths := []string{c.Request().URL.Path}
+	seen := map[string]bool{c.Request().URL.Path: true}
+
+	for _, pattern := range []string{c.Request().URL.Path, c.Path()} {
+		for _, mapped := range config.InvalidatePaths[pattern] {
+			path := expandRouteParams(c, strings.TrimSuffix(mapped, "?*"))
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+		if c.Path() == c.Request().URL.Path {
+			break
+		}
+	}
+	return paths
+}
+
+// expandRouteParams replaces the ":name" segments of the path by the value of the route parameter.
+func expandRouteParams(c echo.Context, path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = c.Param(segment[1:])
+		}
+	}
+	return strings.Join(segments, "/")
+}
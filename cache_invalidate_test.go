@@ -0,0 +1,137 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isUnsafeMethod(t *testing.T) {
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		assert.True(t, isUnsafeMethod(method), method)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		assert.False(t, isUnsafeMethod(method), method)
+	}
+}
+
+func Test_CacheWithConfig_invalidateOnUnsafeMethods(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		status      int
+		invalidated []string
+	}{
+		{
+			name:        "POST invalidates the path with any query string",
+			method:      http.MethodPost,
+			url:         "/api/users",
+			status:      http.StatusCreated,
+			invalidated: []string{"/api/users", "/api/users?page=2"},
+		},
+		{
+			name:        "PUT invalidates the paths mapped to the route",
+			method:      http.MethodPut,
+			url:         "/api/users/42",
+			status:      http.StatusOK,
+			invalidated: []string{"/api/users/42", "/api/users/42?fields=name", "/api/users", "/api/users?page=2"},
+		},
+		{
+			name:        "route parameters of the mapped paths are replaced",
+			method:      http.MethodPut,
+			url:         "/api/users/42/avatar",
+			status:      http.StatusNoContent,
+			invalidated: []string{"/api/users/42", "/api/users/42?fields=name"},
+		},
+		{
+			name:   "failed requests do not invalidate anything",
+			method: http.MethodDelete,
+			url:    "/api/users/42",
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := map[string]int{}
+
+			e := echo.New()
+			e.Use(CacheWithConfig(CacheConfig{
+				Store:                     NewCacheMemoryStore(),
+				Expiration:                5 * time.Second,
+				IncludePaths:              []string{"/api"},
+				InvalidateOnUnsafeMethods: true,
+				InvalidatePaths: map[string][]string{
+					"/api/users/:id":        {"/api/users?*"},
+					"/api/users/:id/avatar": {"/api/users/:id"},
+				},
+			}))
+			get := func(c echo.Context) error {
+				calls[c.Request().URL.String()]++
+				return c.String(http.StatusOK, "test")
+			}
+			unsafe := func(c echo.Context) error {
+				return c.NoContent(tt.status)
+			}
+			e.GET("/api/users", get)
+			e.GET("/api/users/:id", get)
+			e.POST("/api/users", unsafe)
+			e.PUT("/api/users/:id", unsafe)
+			e.PUT("/api/users/:id/avatar", unsafe)
+			e.DELETE("/api/users/:id", unsafe)
+
+			urls := []string{"/api/users", "/api/users?page=2", "/api/users/42", "/api/users/42?fields=name", "/api/users/7"}
+			for _, url := range urls {
+				e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			assert.Equal(t, tt.status, rec.Code)
+
+			for _, url := range urls {
+				e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+			}
+			for _, url := range urls {
+				want := 1
+				for _, invalidated := range tt.invalidated {
+					if invalidated == url {
+						want = 2
+					}
+				}
+				assert.Equal(t, want, calls[url], url)
+			}
+		})
+	}
+}
+
+func Test_CacheWithConfig_invalidateOnUnsafeMethods_tagIndex(t *testing.T) {
+	store := NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{Capacity: 10})
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:                     store,
+		Expiration:                5 * time.Second,
+		IncludePaths:              []string{"/api/"},
+		InvalidateOnUnsafeMethods: true,
+	}))
+	e.GET("/api/*", func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+	e.POST("/api/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	})
+
+	for _, url := range []string{"/api/users", "/api/users?page=2", "/api/users/7"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/users", nil))
+
+	// only the keys of the path are released, along with their index entries
+	assert.Equal(t, 1, store.Size())
+	assert.NotContains(t, store.tags, pathTag("/api/users"))
+	assert.Contains(t, store.tags, pathTag("/api/users/7"))
+}
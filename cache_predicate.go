@@ -0,0 +1,83 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ShouldCacheFunc decides whether a response is cached, from the request context and the
+// response status code, header and body.
+type ShouldCacheFunc func(c echo.Context, status int, header http.Header, body []byte) bool
+
+// CacheIfNonEmptyBody caches the responses with a body. It is the default ShouldCache.
+func CacheIfNonEmptyBody(_ echo.Context, _ int, _ http.Header, body []byte) bool {
+	return len(body) > 0
+}
+
+// CacheIfStatus caches the responses with one of the status codes.
+func CacheIfStatus(codes ...int) ShouldCacheFunc {
+	return func(_ echo.Context, status int, _ http.Header, _ []byte) bool {
+		for _, code := range codes {
+			if code == status {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// CacheIfContentType caches the responses with one of the media types, such as
+// "application/json". A type ending with "/*", such as "text/*", matches every subtype.
+func CacheIfContentType(types ...string) ShouldCacheFunc {
+	return func(_ echo.Context, _ int, header http.Header, _ []byte) bool {
+		mediaType, _, _ := mime.ParseMediaType(header.Get(echo.HeaderContentType))
+		for _, t := range types {
+			if prefix, ok := strings.CutSuffix(t, "*"); ok && strings.HasPrefix(mediaType, prefix) {
+				return true
+			}
+			if strings.EqualFold(mediaType, t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// CacheIfAll caches the responses accepted by every function.
+func CacheIfAll(funcs ...ShouldCacheFunc) ShouldCacheFunc {
+	return func(c echo.Context, status int, header http.Header, body []byte) bool {
+		for _, f := range funcs {
+			if !f(c, status, header, body) {
+				return false
+			}
+		}
+		return true
+	}
+}
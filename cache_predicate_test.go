@@ -0,0 +1,75 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ShouldCacheFunc(t *testing.T) {
+	json := http.Header{echo.HeaderContentType: {echo.MIMEApplicationJSONCharsetUTF8}}
+	text := http.Header{echo.HeaderContentType: {echo.MIMETextPlain}}
+
+	tests := []struct {
+		name   string
+		f      ShouldCacheFunc
+		status int
+		header http.Header
+		body   string
+		want   bool
+	}{
+		{"non empty body", CacheIfNonEmptyBody, http.StatusOK, json, `{"items":[]}`, true},
+		{"JSON with zero values", CacheIfNonEmptyBody, http.StatusOK, json, `{"a":"","b":false}`, true},
+		{"empty body", CacheIfNonEmptyBody, http.StatusOK, json, "", false},
+		{"status listed", CacheIfStatus(http.StatusOK, http.StatusNotFound), http.StatusNotFound, json, "", true},
+		{"status not listed", CacheIfStatus(http.StatusOK), http.StatusCreated, json, "a", false},
+		{"content type", CacheIfContentType(echo.MIMEApplicationJSON), http.StatusOK, json, "a", true},
+		{"content type wildcard", CacheIfContentType("text/*"), http.StatusOK, text, "a", true},
+		{"content type not listed", CacheIfContentType("text/*"), http.StatusOK, json, "a", false},
+		{"all accept", CacheIfAll(CacheIfNonEmptyBody, CacheIfStatus(http.StatusOK)), http.StatusOK, json, "a", true},
+		{"one refuses", CacheIfAll(CacheIfNonEmptyBody, CacheIfStatus(http.StatusOK)), http.StatusOK, json, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			assert.Equal(t, tt.want, tt.f(c, tt.status, tt.header, []byte(tt.body)))
+		})
+	}
+}
+
+func Test_CacheWithConfig_shouldCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		shouldCache ShouldCacheFunc
+		body        string
+		wantCached  bool
+	}{
+		{"empty list is cached by default", nil, `{"items":[]}`, true},
+		{"empty body is not cached by default", nil, "", false},
+		{"custom predicate", func(c echo.Context, _ int, header http.Header, _ []byte) bool {
+			return c.QueryParam("nocache") == "" && header.Get("X-Private") == ""
+		}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewCacheMemoryStore()
+			mw := CacheWithConfig(CacheConfig{
+				Store:        store,
+				Expiration:   5 * time.Second,
+				IncludePaths: []string{"/"},
+				ShouldCache:  tt.shouldCache,
+			})
+
+			_ = mw(func(c echo.Context) error {
+				return c.JSONBlob(http.StatusOK, []byte(tt.body))
+			})(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder()))
+
+			_, ok := store.Get(generateKey(http.MethodGet, "/"))
+			assert.Equal(t, tt.wantCached, ok)
+		})
+	}
+}
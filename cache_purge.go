@@ -0,0 +1,99 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+type (
+	// CachePrefixPurger is implemented by the stores releasing responses by URL path prefix.
+	CachePrefixPurger interface {
+		// PurgePrefix releases the responses whose URL path starts with prefix, such as
+		// "/api/products/", and returns how many were released.
+		PurgePrefix(ctx context.Context, prefix string) (int, error)
+	}
+
+	// CachePatternPurger is implemented by the stores releasing responses by URL path pattern.
+	CachePatternPurger interface {
+		// PurgePattern releases the responses whose URL path matches the glob pattern,
+		// with the syntax of CacheRule.Path, and returns how many were released.
+		PurgePattern(ctx context.Context, pattern string) (int, error)
+	}
+
+	// pathReleaser is implemented by the purgers reporting the keys released by a purge, so that
+	// the two-level store also releases the L1 copies warmed from L2, which L1 did not index.
+	pathReleaser interface {
+		// releasePaths releases the responses whose URL path starts with prefix and matches,
+		// and returns the keys indexed under these paths and how many responses were released.
+		releasePaths(ctx context.Context, prefix string, match func(path string) bool) ([]uint64, int, error)
+	}
+)
+
+// ErrPurgeNotSupported is returned when the store does not support purging by URL path.
+var ErrPurgeNotSupported = errors.New("store does not support purging by URL path")
+
+// PurgePrefix releases the responses cached in the store whose URL path starts with prefix.
+func PurgePrefix(ctx context.Context, store CacheStore, prefix string) (int, error) {
+	purger, ok := StoreAs[CachePrefixPurger](store)
+	if !ok {
+		return 0, ErrPurgeNotSupported
+	}
+	return purger.PurgePrefix(ctx, prefix)
+}
+
+// PurgePattern releases the responses cached in the store whose URL path matches the glob
+// pattern: * matches a path segment or part of it, ** any number of segments and ? a
+// character, such as "/api/products/*/reviews".
+func PurgePattern(ctx context.Context, store CacheStore, pattern string) (int, error) {
+	purger, ok := StoreAs[CachePatternPurger](store)
+	if !ok {
+		return 0, ErrPurgeNotSupported
+	}
+	return purger.PurgePattern(ctx, pattern)
+}
+
+// prefixMatcher returns the matcher of the paths starting with prefix.
+func prefixMatcher(prefix string) func(path string) bool {
+	return func(path string) bool {
+		return strings.HasPrefix(path, prefix)
+	}
+}
+
+// patternMatcher returns the matcher of the paths matching the glob pattern.
+func patternMatcher(pattern string) func(path string) bool {
+	return regexp.MustCompile(globToRegexp(pattern)).MatchString
+}
+
+// globLiteralPrefix returns the part of the glob pattern before its first wildcard.
+func globLiteralPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?"); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
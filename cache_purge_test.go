@@ -0,0 +1,142 @@
+package echo_http_cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/kenshin579/echo-http-cache/test"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPurgeTestServer returns a server caching the responses of /api/ in the store.
+func newPurgeTestServer(store CacheStore) *echo.Echo {
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:        store,
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/api/"},
+	}))
+	e.GET("/api/*", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Request().URL.String())
+	})
+	return e
+}
+
+var purgeTestURLs = []string{
+	"/api/products/1",
+	"/api/products/1?page=2",
+	"/api/products/1/reviews",
+	"/api/products/2/reviews",
+	"/api/users/1",
+}
+
+func Test_PurgePrefix_PurgePattern(t *testing.T) {
+	tests := []struct {
+		name       string
+		purge      func(store CacheStore) (int, error)
+		wantPurged int
+		wantKept   []string
+	}{
+		{
+			name: "prefix",
+			purge: func(store CacheStore) (int, error) {
+				return PurgePrefix(context.Background(), store, "/api/products/")
+			},
+			wantPurged: 4,
+			wantKept:   []string{"/api/users/1"},
+		},
+		{
+			name: "pattern with a segment wildcard",
+			purge: func(store CacheStore) (int, error) {
+				return PurgePattern(context.Background(), store, "/api/products/*/reviews")
+			},
+			wantPurged: 2,
+			wantKept:   []string{"/api/products/1", "/api/products/1?page=2", "/api/users/1"},
+		},
+		{
+			name: "pattern with a path wildcard",
+			purge: func(store CacheStore) (int, error) {
+				return PurgePattern(context.Background(), store, "/api/**/1")
+			},
+			wantPurged: 3,
+			wantKept:   []string{"/api/products/1/reviews", "/api/products/2/reviews"},
+		},
+	}
+	stores := map[string]func(t *testing.T) CacheStore{
+		"memory": func(*testing.T) CacheStore {
+			return NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{Capacity: 10})
+		},
+		"two-level": func(t *testing.T) CacheStore {
+			mredis, _ := test.NewRedisDB()
+			t.Cleanup(mredis.Close)
+			return NewCacheTwoLevelStore(
+				NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{Capacity: 10}),
+				NewCacheRedisStoreWithConfig(redis.Options{Addr: mredis.Addr()}))
+		},
+	}
+	for storeName, newStore := range stores {
+		for _, tt := range tests {
+			t.Run(storeName+" "+tt.name, func(t *testing.T) {
+				store := newStore(t)
+				e := newPurgeTestServer(store)
+				for _, u := range purgeTestURLs {
+					e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+				}
+
+				purged, err := tt.purge(store)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantPurged, purged)
+
+				for _, u := range purgeTestURLs {
+					_, ok := store.Get(generateKey(http.MethodGet, u))
+					assert.Equal(t, slices.Contains(tt.wantKept, u), ok, u)
+				}
+			})
+		}
+	}
+}
+
+func Test_CacheMemoryStore_purgePathIndex(t *testing.T) {
+	store := NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{Capacity: 10})
+	e := newPurgeTestServer(store)
+	for _, u := range purgeTestURLs {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+	}
+
+	// the responses are found through the path tags, which leave the index once purged
+	purged, err := store.PurgePrefix(context.Background(), "/api/products/1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	assert.NotContains(t, store.tags, pathTag("/api/products/1"))
+	assert.Contains(t, store.tags, pathTag("/api/products/2/reviews"))
+
+	// responses stored without their path tag are not purged
+	store.Set(generateKey(http.MethodGet, "/api/orders"), CacheResponse{URL: "/api/orders"}.bytes(), time.Now().Add(time.Minute))
+	purged, err = store.PurgePattern(context.Background(), "/api/orders")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, purged)
+}
+
+func Test_PurgePrefix_notSupported(t *testing.T) {
+	store := struct{ CacheStore }{NewCacheMemoryStore()}
+
+	_, err := PurgePrefix(context.Background(), store, "/api/")
+	assert.Equal(t, ErrPurgeNotSupported, err)
+	_, err = PurgePattern(context.Background(), store, "/api/*")
+	assert.Equal(t, ErrPurgeNotSupported, err)
+
+	_, err = PurgePrefix(context.Background(), NewCacheTwoLevelStore(store, store), "/api/")
+	assert.Equal(t, ErrPurgeNotSupported, err)
+}
+
+func Test_globLiteralPrefix(t *testing.T) {
+	assert.Equal(t, "/api/products/", globLiteralPrefix("/api/products/*/reviews"))
+	assert.Equal(t, "/api/", globLiteralPrefix("/api/?"))
+	assert.Equal(t, "/api/products", globLiteralPrefix("/api/products"))
+}
@@ -0,0 +1,124 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// contextKeyMiddleware is the echo context key of the global cache middleware, set when
+// none of its rules matches the request so that a Route middleware can cache it instead.
+const contextKeyMiddleware = "echo-http-cache.middleware"
+
+// RouteOption configures the caching of a route with Route.
+type RouteOption func(rule *CacheRule)
+
+/*
+Route returns a route-level cache middleware, which caches the GET and HEAD requests of
+the route with the store, metrics and settings of the global cache middleware and its
+own options. The global middleware must be installed with e.Use, and its rules matching
+the request take precedence. Without global middleware, requests are not cached.
+
+	e.Use(echocache.CacheWithConfig(config))
+	e.GET("/api/data", h, echocache.Route(echocache.TTL(30*time.Second), echocache.Tags("data")))
+*/
+func Route(options ...RouteOption) echo.MiddlewareFunc {
+	var rule CacheRule
+	for _, option := range options {
+		option(&rule)
+	}
+
+	// rule compiled with the defaults of each global middleware
+	var compiled sync.Map
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			m, _ := c.Get(contextKeyMiddleware).(*cacheMiddleware)
+			method := c.Request().Method
+			if m == nil || (method != http.MethodGet && method != http.MethodHead) {
+				return next(c)
+			}
+
+			r, ok := compiled.Load(m)
+			if !ok {
+				r, _ = compiled.LoadOrStore(m, compileRule(m.config, rule))
+			}
+			return m.serve(c, next, r.(*CacheRule))
+		}
+	}
+}
+
+// TTL sets the expiration of the responses of the route.
+func TTL(expiration time.Duration) RouteOption {
+	return func(rule *CacheRule) {
+		rule.Expiration = expiration
+	}
+}
+
+// NegativeTTL sets the expiration of the 4xx responses of the route.
+func NegativeTTL(expiration time.Duration) RouteOption {
+	return func(rule *CacheRule) {
+		rule.NegativeExpiration = expiration
+	}
+}
+
+// Tags adds tags to the responses of the route, so that they can be released with InvalidateTags.
+func Tags(tags ...string) RouteOption {
+	return func(rule *CacheRule) {
+		rule.Tags = append(rule.Tags, tags...)
+	}
+}
+
+// CacheableStatusCodes sets the response status codes of the route that can be cached.
+func CacheableStatusCodes(codes ...int) RouteOption {
+	return func(rule *CacheRule) {
+		rule.CacheableStatusCodes = append(rule.CacheableStatusCodes, codes...)
+	}
+}
+
+// KeyGenerator sets the function returning the value the cache keys of the route are computed from.
+func KeyGenerator(generator func(c echo.Context) (string, bool)) RouteOption {
+	return func(rule *CacheRule) {
+		rule.KeyGenerator = generator
+	}
+}
+
+// KeyQueryParams sets the query parameters the cache keys of the route are computed from.
+func KeyQueryParams(names ...string) RouteOption {
+	return func(rule *CacheRule) {
+		rule.KeyQueryParams = append(rule.KeyQueryParams, names...)
+	}
+}
+
+// KeyHeaders adds request headers to the cache keys of the route.
+func KeyHeaders(names ...string) RouteOption {
+	return func(rule *CacheRule) {
+		rule.KeyHeaders = append(rule.KeyHeaders, names...)
+	}
+}
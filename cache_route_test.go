@@ -0,0 +1,69 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Route(t *testing.T) {
+	store := NewCacheMemoryStore()
+	calls := map[string]int{}
+
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:        store,
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/global"},
+	}))
+	handler := func(c echo.Context) error {
+		calls[c.Request().URL.Path]++
+		return c.String(http.StatusOK, "test")
+	}
+	e.GET("/api/data", handler, Route(TTL(time.Hour), Tags("data"), KeyQueryParams("page")))
+	e.GET("/global/data", handler, Route(TTL(time.Hour)))
+	e.GET("/uncached", handler)
+
+	for _, url := range []string{"/api/data?page=1", "/api/data?page=1&utm=x", "/global/data", "/global/data", "/uncached", "/uncached"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "test", rec.Body.String())
+	}
+	assert.Equal(t, map[string]int{"/api/data": 1, "/global/data": 1, "/uncached": 2}, calls)
+
+	// the options of the route apply
+	cached, ok := store.Get(generateKey(http.MethodGet, "/api/data?page=1"))
+	assert.True(t, ok)
+	assert.Equal(t, []string{"data"}, toCacheResponse(cached).Tags)
+	assert.True(t, time.Until(toCacheResponse(cached).Expiration) > time.Minute)
+
+	// the global rule takes precedence
+	cached, ok = store.Get(generateKey(http.MethodGet, "/global/data"))
+	assert.True(t, ok)
+	assert.True(t, time.Until(toCacheResponse(cached).Expiration) < time.Minute)
+
+	assert.NoError(t, InvalidateTags(store, "data"))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/data?page=1", nil))
+	assert.Equal(t, 2, calls["/api/data"])
+}
+
+func Test_Route_withoutGlobalMiddleware(t *testing.T) {
+	calls := 0
+	e := echo.New()
+	e.GET("/api/data", func(c echo.Context) error {
+		calls++
+		return c.String(http.StatusOK, "test")
+	}, Route(TTL(time.Hour)))
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/data", nil))
+		assert.Equal(t, "test", rec.Body.String())
+	}
+	assert.Equal(t, 2, calls)
+}
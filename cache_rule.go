@@ -0,0 +1,281 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CacheRule selects the requests it applies to and how their responses are cached.
+// A rule matches a request when its method and every matcher set (Route, Path and
+// Regexp) match. A rule without matchers matches every request of its methods.
+type CacheRule struct {
+	// Methods the rule applies to: GET, HEAD or POST. HEAD requests match the rules
+	// of GET requests. Defaults to GET and HEAD.
+	Methods []string
+
+	// Route matches the echo route pattern of the request, e.g. "/api/user/:id".
+	Route string
+
+	// Path is a glob matched against the request path, where "*" matches a path
+	// segment or a part of it, "**" matches any number of segments and "?" matches a
+	// single character, e.g. "/api/**" or "/static/*.css".
+	Path string
+
+	// Regexp is a regular expression matched against the request path.
+	Regexp string
+
+	// Exclude passes the matching requests to the next handler without caching them.
+	Exclude bool
+
+	// Expiration of the responses. Defaults to CacheConfig.Expiration.
+	Expiration time.Duration
+
+	// CacheableStatusCodes lists the response status codes that can be cached.
+	// Defaults to CacheConfig.CacheableStatusCodes.
+	CacheableStatusCodes []int
+
+	// NegativeExpiration is the expiration used for 4xx responses.
+	// Defaults to CacheConfig.NegativeExpiration.
+	NegativeExpiration time.Duration
+
+	// KeyGenerator returns the value the cache key is computed from. Defaults to
+	// CacheConfig.KeyGenerator.
+	KeyGenerator func(c echo.Context) (string, bool)
+
+	// KeyQueryParams lists the query parameters the cache key is computed from,
+	// the others being ignored. When empty, every query parameter is part of the key.
+	KeyQueryParams []string
+
+	// KeyHeaders lists the request headers whose values are added to the cache key.
+	KeyHeaders []string
+
+	// Tags are added to the tags of the cached responses.
+	Tags []string
+
+	// contains matches the rules of the legacy IncludePaths, IncludePathsWithExpiration,
+	// IncludePOSTPaths and ExcludePaths fields, which are substrings of the request URL.
+	contains string
+
+	pathPattern *regexp.Regexp
+	pattern     *regexp.Regexp
+}
+
+// compileRules returns the rules of the config followed by the rules of its legacy fields,
+// with their patterns compiled and the config defaults applied. It panics on an invalid pattern.
+func compileRules(config CacheConfig) []*CacheRule {
+	rules := make([]*CacheRule, 0, len(config.Rules))
+	for _, rule := range append(append([]CacheRule{}, config.Rules...), legacyRules(config)...) {
+		rules = append(rules, compileRule(config, rule))
+	}
+	return rules
+}
+
+// compileRule compiles the patterns of the rule and applies the config defaults.
+func compileRule(config CacheConfig, rule CacheRule) *CacheRule {
+	if len(rule.Methods) == 0 {
+		rule.Methods = []string{http.MethodGet, http.MethodHead}
+	}
+	if rule.Path != "" {
+		rule.pathPattern = regexp.MustCompile(globToRegexp(rule.Path))
+	}
+	if rule.Regexp != "" {
+		pattern, err := regexp.Compile(rule.Regexp)
+		if err != nil {
+			panic(fmt.Sprintf("invalid cache rule regexp %q: %v", rule.Regexp, err))
+		}
+		rule.pattern = pattern
+	}
+	if rule.Expiration == 0 {
+		rule.Expiration = config.Expiration
+	}
+	if len(rule.CacheableStatusCodes) == 0 {
+		rule.CacheableStatusCodes = config.CacheableStatusCodes
+	}
+	if rule.NegativeExpiration == 0 {
+		rule.NegativeExpiration = config.NegativeExpiration
+	}
+	if rule.KeyGenerator == nil {
+		rule.KeyGenerator = config.KeyGenerator
+	}
+	return &rule
+}
+
+// legacyRules converts the IncludePaths, IncludePathsWithExpiration, IncludePOSTPaths and
+// ExcludePaths fields into rules. Exclusions come first, then the paths with an expiration
+// from the longest to the shortest, so that the most specific one wins.
+func legacyRules(config CacheConfig) []CacheRule {
+	var rules []CacheRule
+	for _, p := range config.ExcludePaths {
+		rules = append(rules, CacheRule{
+			Methods:  []string{http.MethodGet, http.MethodHead, http.MethodPost},
+			Exclude:  true,
+			contains: p,
+		})
+	}
+
+	paths := make([]string, 0, len(config.IncludePathsWithExpiration))
+	for p := range config.IncludePathsWithExpiration {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) > len(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+	for _, p := range paths {
+		rules = append(rules, CacheRule{Expiration: config.IncludePathsWithExpiration[p], contains: p})
+	}
+
+	for _, p := range config.IncludePaths {
+		rules = append(rules, CacheRule{contains: p})
+	}
+	for _, p := range config.IncludePOSTPaths {
+		rules = append(rules, CacheRule{Methods: []string{http.MethodPost}, contains: p})
+	}
+	return rules
+}
+
+// matchRule returns the first rule matching the request, or nil.
+func matchRule(rules []*CacheRule, c echo.Context) *CacheRule {
+	for _, rule := range rules {
+		if rule.matches(c) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (rule *CacheRule) matches(c echo.Context) bool {
+	req := c.Request()
+	if !rule.matchesMethod(req.Method) {
+		return false
+	}
+	if rule.Route != "" && rule.Route != c.Path() {
+		return false
+	}
+	if rule.pathPattern != nil && !rule.pathPattern.MatchString(req.URL.Path) {
+		return false
+	}
+	if rule.pattern != nil && !rule.pattern.MatchString(req.URL.Path) {
+		return false
+	}
+	if rule.contains != "" && !strings.Contains(req.URL.String(), rule.contains) {
+		return false
+	}
+	return true
+}
+
+func (rule *CacheRule) matchesMethod(method string) bool {
+	for _, m := range rule.Methods {
+		if m == method || (method == http.MethodHead && m == http.MethodGet) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyValue returns the value the cache key is computed from, applying the key options of the rule.
+func (rule *CacheRule) keyValue(c echo.Context) (string, bool) {
+	req := c.Request()
+	if len(rule.KeyQueryParams) > 0 {
+		// the key generator sees the URL with the listed query parameters only
+		query := req.URL.Query()
+		filtered := url.Values{}
+		for _, name := range rule.KeyQueryParams {
+			if values, ok := query[name]; ok {
+				filtered[name] = values
+			}
+		}
+
+		keyURL := *req.URL
+		keyURL.RawQuery = filtered.Encode()
+		keyReq := *req
+		keyReq.URL = &keyURL
+
+		c.SetRequest(&keyReq)
+		defer c.SetRequest(req)
+	}
+
+	value, ok := rule.KeyGenerator(c)
+	if !ok {
+		return "", false
+	}
+
+	for _, name := range rule.KeyHeaders {
+		value += "\n" + http.CanonicalHeaderKey(name) + ": " + strings.Join(req.Header.Values(name), ",")
+	}
+	return value, true
+}
+
+func (rule *CacheRule) isCacheableStatusCode(statusCode int) bool {
+	if len(rule.CacheableStatusCodes) == 0 {
+		return statusCode < http.StatusBadRequest
+	}
+
+	for _, code := range rule.CacheableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *CacheRule) getExpiration(now time.Time, statusCode int) time.Time {
+	if rule.NegativeExpiration > 0 && statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError {
+		return now.Add(rule.NegativeExpiration)
+	}
+	return now.Add(rule.Expiration)
+}
+
+// globToRegexp converts a Path glob into a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
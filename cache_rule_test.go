@@ -0,0 +1,139 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_globToRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"/api/*", "/api/users", true},
+		{"/api/*", "/api/users/42", false},
+		{"/api/**", "/api/users/42", true},
+		{"/static/*.css", "/static/main.css", true},
+		{"/static/*.css", "/static/main.js", false},
+		{"/v?/users", "/v2/users", true},
+		{"/a.b", "/aXb", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.path, func(t *testing.T) {
+			rule := compileRules(CacheConfig{Rules: []CacheRule{{Path: tt.glob}}})[0]
+			assert.Equal(t, tt.match, rule.pathPattern.MatchString(tt.path))
+		})
+	}
+}
+
+func Test_compileRules_invalidRegexp(t *testing.T) {
+	assert.Panics(t, func() {
+		compileRules(CacheConfig{Rules: []CacheRule{{Regexp: "("}}})
+	})
+}
+
+func Test_legacyRules(t *testing.T) {
+	config := CacheConfig{
+		Expiration: time.Minute,
+		IncludePathsWithExpiration: map[string]time.Duration{
+			"/test1":       1 * time.Second,
+			"/test2":       2 * time.Second,
+			"/test1/inner": 3 * time.Second,
+		},
+		IncludePaths: []string{"/test"},
+		ExcludePaths: []string{"/test1/excluded"},
+	}
+	rules := compileRules(config)
+
+	tests := []struct {
+		url        string
+		exclude    bool
+		expiration time.Duration
+	}{
+		{"/test1", false, 1 * time.Second},
+		{"/test2", false, 2 * time.Second},
+		{"/test1/inner", false, 3 * time.Second},
+		{"/test3", false, time.Minute},
+		{"/test1/excluded", true, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, tt.url, nil), nil)
+			rule := matchRule(rules, c)
+
+			assert.NotNil(t, rule)
+			assert.Equal(t, tt.exclude, rule.Exclude)
+			assert.Equal(t, tt.expiration, rule.Expiration)
+		})
+	}
+
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/other", nil), nil)
+	assert.Nil(t, matchRule(rules, c))
+}
+
+func Test_CacheWithConfig_rules(t *testing.T) {
+	store := NewCacheMemoryStore()
+	calls := map[string]int{}
+
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:      store,
+		Expiration: 5 * time.Second,
+		Rules: []CacheRule{
+			{Route: "/api/user/:id", Exclude: true, Methods: []string{http.MethodGet}, KeyHeaders: []string{"X-Ignored"}},
+			{Route: "/api/item/:id", KeyQueryParams: []string{"fields"}, KeyHeaders: []string{"X-Tenant"}},
+			{Path: "/static/**", Expiration: time.Hour, CacheableStatusCodes: []int{http.StatusOK}},
+			{Regexp: `^/search$`, Methods: []string{http.MethodPost}},
+		},
+	}))
+	handler := func(c echo.Context) error {
+		calls[c.Request().Method+" "+c.Request().URL.Path]++
+		return c.String(http.StatusOK, "test")
+	}
+	e.GET("/api/user/:id", handler)
+	e.GET("/api/item/:id", handler)
+	e.GET("/static/*", handler)
+	e.GET("/search", handler)
+	e.POST("/search", handler)
+
+	request := func(method, url string, header http.Header) {
+		req := httptest.NewRequest(method, url, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	tenant1 := http.Header{"X-Tenant": {"1"}}
+	tenant2 := http.Header{"X-Tenant": {"2"}}
+	for i := 0; i < 2; i++ {
+		request(http.MethodGet, "/api/user/1", nil)
+		request(http.MethodGet, "/api/item/1?fields=name&utm_source=a", tenant1)
+		request(http.MethodGet, "/api/item/1?utm_source=b&fields=name", tenant1)
+		request(http.MethodGet, "/api/item/1?fields=name", tenant2)
+		request(http.MethodGet, "/static/css/main.css", nil)
+		request(http.MethodGet, "/search", nil)
+		request(http.MethodPost, "/search", nil)
+	}
+
+	assert.Equal(t, map[string]int{
+		"GET /api/user/1":          2,
+		"GET /api/item/1":          2,
+		"GET /static/css/main.css": 1,
+		"GET /search":              2,
+		"POST /search":             1,
+	}, calls)
+
+	// the expiration of the matching rule is used
+	cached, ok := store.Get(generateKey(http.MethodGet, "/static/css/main.css"))
+	assert.True(t, ok)
+	assert.True(t, time.Until(toCacheResponse(cached).Expiration) > time.Minute)
+}
@@ -41,6 +41,11 @@ type CacheStats struct {
 	L1Size       int       `json:"l1Size"`
 	L2Size       int       `json:"l2Size"`
 	LastUpdate   time.Time `json:"lastUpdate"`
+
+	// Coalesced is the number of requests answered with the response of a concurrent request
+	Coalesced int64 `json:"coalesced"`
+	// CoalesceWait is the total time coalesced requests waited for the response
+	CoalesceWait time.Duration `json:"coalesceWait"`
 }
 
 // CacheMetrics holds atomic counters for thread-safe statistics
@@ -49,6 +54,8 @@ type CacheMetrics struct {
 	l2Hits       int64
 	totalMiss    int64
 	totalRequest int64
+	coalesced    int64
+	coalesceWait int64
 }
 
 // IncrementL1Hit atomically increments L1 hit counter
@@ -69,6 +76,12 @@ func (m *CacheMetrics) IncrementMiss() {
 	atomic.AddInt64(&m.totalRequest, 1)
 }
 
+// IncrementCoalesced atomically increments coalesced request counter and adds the time it waited
+func (m *CacheMetrics) IncrementCoalesced(wait time.Duration) {
+	atomic.AddInt64(&m.coalesced, 1)
+	atomic.AddInt64(&m.coalesceWait, int64(wait))
+}
+
 // GetStats returns current statistics
 func (m *CacheMetrics) GetStats() CacheStats {
 	l1Hits := atomic.LoadInt64(&m.l1Hits)
@@ -92,6 +105,8 @@ func (m *CacheMetrics) GetStats() CacheStats {
 		L1HitRate:    l1HitRate,
 		L2HitRate:    l2HitRate,
 		LastUpdate:   time.Now(),
+		Coalesced:    atomic.LoadInt64(&m.coalesced),
+		CoalesceWait: time.Duration(atomic.LoadInt64(&m.coalesceWait)),
 	}
 }
 
@@ -101,4 +116,6 @@ func (m *CacheMetrics) Reset() {
 	atomic.StoreInt64(&m.l2Hits, 0)
 	atomic.StoreInt64(&m.totalMiss, 0)
 	atomic.StoreInt64(&m.totalRequest, 0)
+	atomic.StoreInt64(&m.coalesced, 0)
+	atomic.StoreInt64(&m.coalesceWait, 0)
 }
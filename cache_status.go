@@ -0,0 +1,79 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderCacheStatus is the RFC 9211 Cache-Status response header.
+	HeaderCacheStatus = "Cache-Status"
+
+	// HeaderXCache is the X-Cache response header, set when CacheConfig.XCache is enabled.
+	HeaderXCache = "X-Cache"
+)
+
+// X-Cache header values.
+const (
+	XCacheHit    = "HIT"
+	XCacheMiss   = "MISS"
+	XCacheStale  = "STALE"
+	XCacheBypass = "BYPASS"
+)
+
+// setCacheStatus sets the Cache-Status header with the parameters, and the X-Cache header when enabled.
+func (m *cacheMiddleware) setCacheStatus(c echo.Context, xCache string, params ...string) {
+	if !m.config.DisableCacheStatus {
+		value := strings.Join(append([]string{m.config.CacheStatusName}, params...), "; ")
+		c.Response().Header().Set(HeaderCacheStatus, value)
+	}
+	if m.config.XCache {
+		c.Response().Header().Set(HeaderXCache, xCache)
+	}
+}
+
+// setHitStatus sets the Cache-Status, X-Cache and Age headers of a response served from the cache.
+func (m *cacheMiddleware) setHitStatus(c echo.Context, xCache string, cached CacheResponse, level string, now time.Time, params ...string) {
+	params = append([]string{"hit", fmt.Sprintf("ttl=%d", int64(math.Floor(cached.Expiration.Sub(now).Seconds())))}, params...)
+	if level != "" {
+		params = append(params, "detail="+level)
+	}
+	m.setCacheStatus(c, xCache, params...)
+
+	if storedAt := cached.storedAt(); !storedAt.IsZero() {
+		age := int64(now.Sub(storedAt).Seconds())
+		if age < 0 {
+			age = 0
+		}
+		c.Response().Header().Set("Age", strconv.FormatInt(age, 10))
+	}
+}
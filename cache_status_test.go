@@ -0,0 +1,122 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CacheWithConfig_cacheStatus(t *testing.T) {
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:      NewCacheMemoryStore(),
+		Expiration: time.Minute,
+		XCache:     true,
+		Rules: []CacheRule{
+			{Path: "/excluded", Exclude: true},
+			{Path: "/**"},
+		},
+	}))
+	handler := func(c echo.Context) error {
+		c.Response().Header().Set("Date", time.Now().Add(-10*time.Second).UTC().Format(http.TimeFormat))
+		return c.String(http.StatusOK, "test")
+	}
+	e.GET("/cached", handler)
+	e.GET("/excluded", handler)
+
+	tests := []struct {
+		name            string
+		url             string
+		wantCacheStatus string
+		wantXCache      string
+		wantAge         string
+	}{
+		{"miss", "/cached", "echo-http-cache; fwd=uri-miss", XCacheMiss, ""},
+		{"hit", "/cached", "echo-http-cache; hit; ttl=59", XCacheHit, "10"},
+		{"bypass", "/excluded", "echo-http-cache; fwd=bypass", XCacheBypass, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantCacheStatus, rec.Header().Get(HeaderCacheStatus))
+			assert.Equal(t, tt.wantXCache, rec.Header().Get(HeaderXCache))
+			assert.Equal(t, tt.wantAge, rec.Header().Get("Age"))
+		})
+	}
+}
+
+func Test_CacheWithConfig_cacheStatusStale(t *testing.T) {
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:                NewCacheMemoryStore(),
+		Expiration:           time.Second,
+		IncludePaths:         []string{"/stale"},
+		StaleWhileRevalidate: time.Minute,
+		CacheStatusName:      "api",
+		XCache:               true,
+	}))
+	e.GET("/stale", func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stale", nil))
+	time.Sleep(1100 * time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stale", nil))
+	assert.Regexp(t, `^api; hit; ttl=-\d+$`, rec.Header().Get(HeaderCacheStatus))
+	assert.Equal(t, XCacheStale, rec.Header().Get(HeaderXCache))
+}
+
+func Test_CacheWithConfig_cacheStatusLevel(t *testing.T) {
+	l1 := NewCacheMemoryStore()
+	store := NewCacheTwoLevelStoreWithConfig(TwoLevelConfig{
+		L1Store:  l1,
+		L2Store:  NewCacheMemoryStore(),
+		Strategy: WriteThrough,
+	})
+
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:        store,
+		Expiration:   time.Minute,
+		IncludePaths: []string{"/level"},
+	}))
+	e.GET("/level", func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/level", nil))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/level", nil))
+	assert.Regexp(t, `; detail=L1$`, rec.Header().Get(HeaderCacheStatus))
+	assert.Empty(t, rec.Header().Get(HeaderXCache))
+
+	_ = l1.Clear()
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/level", nil))
+	assert.Regexp(t, `; detail=L2$`, rec.Header().Get(HeaderCacheStatus))
+}
+
+func Test_CacheWithConfig_disableCacheStatus(t *testing.T) {
+	mw := CacheWithConfig(CacheConfig{
+		Store:              NewCacheMemoryStore(),
+		Expiration:         time.Minute,
+		IncludePaths:       []string{"/"},
+		DisableCacheStatus: true,
+	})
+
+	rec := httptest.NewRecorder()
+	_ = mw(func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+
+	assert.Empty(t, rec.Header().Get(HeaderCacheStatus))
+}
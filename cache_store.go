@@ -0,0 +1,227 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	// CacheStoreV2 is the context-aware version of CacheStore, reporting the failures of
+	// the store. A missing key is not a failure. The built-in stores implement both
+	// interfaces, ToCacheStoreV2 and FromCacheStoreV2 adapt other stores.
+	CacheStoreV2 interface {
+		// GetContext retrieves the cached response by a given key. It also
+		// returns true or false, whether it exists or not.
+		GetContext(ctx context.Context, key uint64) ([]byte, bool, error)
+
+		// GetWithTTL works like GetContext and also returns the remaining time to live
+		// of the key, or 0 when it is unknown or the key does not expire.
+		GetWithTTL(ctx context.Context, key uint64) ([]byte, time.Duration, bool, error)
+
+		// SetContext caches a response for a given key until an Expiration date.
+		SetContext(ctx context.Context, key uint64, response []byte, expiration time.Time) error
+
+		// Delete frees cache for a given key.
+		Delete(ctx context.Context, key uint64) error
+
+		// Exists reports whether a response is cached for a given key.
+		Exists(ctx context.Context, key uint64) (bool, error)
+	}
+
+	// CacheStoreWrapper is implemented by the stores wrapping another store, such as the
+	// instrumented stores of the prometheus and opentelemetry packages. The optional
+	// interfaces of the wrapped store, such as CacheTagStore, are found through Unwrap.
+	CacheStoreWrapper interface {
+		// Unwrap returns the wrapped store.
+		Unwrap() CacheStore
+	}
+)
+
+// ErrClearNotSupported is returned when the store cannot be cleared.
+var ErrClearNotSupported = errors.New("store does not support clearing")
+
+// StoreError is the error of a store operation reported to CacheConfig.ErrorHandler.
+type StoreError struct {
+	// Op is the failed operation: get, set, delete or invalidate.
+	Op string
+
+	// Key is the key of the operation, 0 for invalidate.
+	Key uint64
+
+	Err error
+}
+
+func (e *StoreError) Error() string {
+	if e.Op == "invalidate" {
+		return "echo-http-cache: store invalidate: " + e.Err.Error()
+	}
+	return "echo-http-cache: store " + e.Op + " " + keyAsString(e.Key) + ": " + e.Err.Error()
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultCacheErrorHandler logs the error with the echo logger.
+func DefaultCacheErrorHandler(c echo.Context, err error) {
+	c.Logger().Error(err)
+}
+
+// ToCacheStoreV2 returns the store as a CacheStoreV2. Stores which do not implement
+// CacheStoreV2 are adapted: they never fail and their time to live is unknown.
+func ToCacheStoreV2(store CacheStore) CacheStoreV2 {
+	if v2, ok := store.(CacheStoreV2); ok {
+		return v2
+	}
+	if adapter, ok := store.(*storeV2Adapter); ok {
+		return adapter.store
+	}
+	return &storeAdapter{store}
+}
+
+// FromCacheStoreV2 returns the store as a CacheStore, for the middleware configuration
+// or code written for CacheStore. The failures of the store are passed to onError when
+// it is not nil, and reported as misses.
+func FromCacheStoreV2(store CacheStoreV2, onError func(err error)) CacheStore {
+	if v1, ok := store.(CacheStore); ok && onError == nil {
+		return v1
+	}
+	if adapter, ok := store.(*storeAdapter); ok {
+		return adapter.store
+	}
+	return &storeV2Adapter{store: store, onError: onError}
+}
+
+// StoreAs returns the first store implementing T in the chain of the store and the stores
+// it wraps, the way errors.As finds an error. It is used instead of a type assertion to
+// find the optional interfaces of a store, such as CacheTagStore or CacheKeyLister.
+func StoreAs[T any](store CacheStore) (T, bool) {
+	for store != nil {
+		if t, ok := store.(T); ok {
+			return t, true
+		}
+		wrapper, ok := store.(CacheStoreWrapper)
+		if !ok {
+			break
+		}
+		store = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// clearStore removes all the entries of the store, returning ErrClearNotSupported when
+// the store cannot be cleared.
+func clearStore(store CacheStore) error {
+	clearer, ok := StoreAs[interface{ Clear() error }](store)
+	if !ok {
+		return ErrClearNotSupported
+	}
+	return clearer.Clear()
+}
+
+// storeAdapter adapts a CacheStore to CacheStoreV2.
+type storeAdapter struct {
+	store CacheStore
+}
+
+func (a *storeAdapter) GetContext(_ context.Context, key uint64) ([]byte, bool, error) {
+	data, ok := a.store.Get(key)
+	return data, ok, nil
+}
+
+func (a *storeAdapter) GetWithTTL(_ context.Context, key uint64) ([]byte, time.Duration, bool, error) {
+	data, ok := a.store.Get(key)
+	return data, 0, ok, nil
+}
+
+func (a *storeAdapter) SetContext(_ context.Context, key uint64, response []byte, expiration time.Time) error {
+	a.store.Set(key, response, expiration)
+	return nil
+}
+
+func (a *storeAdapter) Delete(_ context.Context, key uint64) error {
+	a.store.Release(key)
+	return nil
+}
+
+func (a *storeAdapter) Exists(_ context.Context, key uint64) (bool, error) {
+	_, ok := a.store.Get(key)
+	return ok, nil
+}
+
+// storeV2Adapter adapts a CacheStoreV2 to CacheStore.
+type storeV2Adapter struct {
+	store   CacheStoreV2
+	onError func(err error)
+}
+
+func (a *storeV2Adapter) Get(key uint64) ([]byte, bool) {
+	data, ok, err := a.store.GetContext(context.Background(), key)
+	a.report(err)
+	return data, ok && err == nil
+}
+
+func (a *storeV2Adapter) Set(key uint64, response []byte, expiration time.Time) {
+	a.report(a.store.SetContext(context.Background(), key, response, expiration))
+}
+
+func (a *storeV2Adapter) Release(key uint64) {
+	a.report(a.store.Delete(context.Background(), key))
+}
+
+// Unwrap implements the CacheStoreWrapper interface, when the adapted store is a CacheStore.
+func (a *storeV2Adapter) Unwrap() CacheStore {
+	store, _ := a.store.(CacheStore)
+	return store
+}
+
+func (a *storeV2Adapter) report(err error) {
+	if err != nil && a.onError != nil {
+		a.onError(err)
+	}
+}
+
+// levelContextKey is the context key of the level recorded by the stores with several levels.
+type levelContextKey struct{}
+
+// withLevelRecorder returns a context in which CacheTwoLevelStore records the level a
+// response is read from.
+func withLevelRecorder(ctx context.Context) (context.Context, *string) {
+	level := new(string)
+	return context.WithValue(ctx, levelContextKey{}, level), level
+}
+
+// recordLevel records the level a response is read from, when the context has a recorder.
+func recordLevel(ctx context.Context, level string) {
+	if recorder, ok := ctx.Value(levelContextKey{}).(*string); ok {
+		*recorder = level
+	}
+}
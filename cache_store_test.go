@@ -0,0 +1,154 @@
+package echo_http_cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore is a CacheStoreV2 failing every operation.
+type failingStore struct {
+	err error
+}
+
+func (s failingStore) GetContext(context.Context, uint64) ([]byte, bool, error) {
+	return nil, false, s.err
+}
+
+func (s failingStore) GetWithTTL(context.Context, uint64) ([]byte, time.Duration, bool, error) {
+	return nil, 0, false, s.err
+}
+
+func (s failingStore) SetContext(context.Context, uint64, []byte, time.Time) error {
+	return s.err
+}
+
+func (s failingStore) Delete(context.Context, uint64) error {
+	return s.err
+}
+
+func (s failingStore) Exists(context.Context, uint64) (bool, error) {
+	return false, s.err
+}
+
+// wrappingStore is a CacheStoreWrapper hiding the optional interfaces of the store.
+type wrappingStore struct {
+	CacheStore
+}
+
+func (s wrappingStore) Unwrap() CacheStore {
+	return s.CacheStore
+}
+
+func Test_StoreAs(t *testing.T) {
+	memory := NewCacheMemoryStore()
+
+	tagStore, ok := StoreAs[CacheTagStore](wrappingStore{wrappingStore{memory}})
+	assert.True(t, ok)
+	assert.Same(t, memory, tagStore)
+
+	memoryStore, ok := StoreAs[*CacheMemoryStore](FromCacheStoreV2(memory, func(error) {}))
+	assert.True(t, ok)
+	assert.Same(t, memory, memoryStore)
+
+	_, ok = StoreAs[CacheTagStore](wrappingStore{untaggedStore{memory}})
+	assert.False(t, ok)
+	_, ok = StoreAs[CacheTagStore](FromCacheStoreV2(failingStore{}, func(error) {}))
+	assert.False(t, ok)
+}
+
+func Test_ToCacheStoreV2(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("built-in stores are returned as they are", func(t *testing.T) {
+		store := NewCacheMemoryStore()
+		assert.Same(t, store, ToCacheStoreV2(store))
+	})
+
+	t.Run("other stores are adapted", func(t *testing.T) {
+		store := ToCacheStoreV2(struct{ CacheStore }{NewCacheMemoryStore()})
+
+		assert.NoError(t, store.SetContext(ctx, 1, []byte("test"), time.Now().Add(time.Minute)))
+		data, ttl, ok, err := store.GetWithTTL(ctx, 1)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "test", string(data))
+		assert.Equal(t, time.Duration(0), ttl)
+
+		assert.NoError(t, store.Delete(ctx, 1))
+		exists, err := store.Exists(ctx, 1)
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("adapters are unwrapped", func(t *testing.T) {
+		store := failingStore{}
+		assert.Equal(t, store, ToCacheStoreV2(FromCacheStoreV2(store, nil)))
+	})
+}
+
+func Test_FromCacheStoreV2(t *testing.T) {
+	storeErr := errors.New("connection refused")
+	var reported []error
+	store := FromCacheStoreV2(failingStore{storeErr}, func(err error) {
+		reported = append(reported, err)
+	})
+
+	_, ok := store.Get(1)
+	assert.False(t, ok)
+	store.Set(1, []byte("test"), time.Now().Add(time.Minute))
+	store.Release(1)
+
+	assert.Equal(t, []error{storeErr, storeErr, storeErr}, reported)
+}
+
+func Test_CacheWithConfig_storeErrors(t *testing.T) {
+	var calls int32
+	var reported []error
+
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:        FromCacheStoreV2(failingStore{errors.New("connection refused")}, nil),
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/test"},
+		ErrorHandler: func(c echo.Context, err error) {
+			reported = append(reported, err)
+		},
+	}))
+	e.GET("/test", func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return c.String(http.StatusOK, "test")
+	})
+
+	// the middleware fails open, the handler serving every request
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "test", rec.Body.String())
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	var ops []string
+	for _, err := range reported {
+		var storeErr *StoreError
+		if assert.ErrorAs(t, err, &storeErr) {
+			ops = append(ops, storeErr.Op)
+			assert.Equal(t, generateKey(http.MethodGet, "/test"), storeErr.Key)
+		}
+	}
+	assert.Equal(t, []string{"get", "set", "get", "set"}, ops)
+}
+
+func Test_StoreError(t *testing.T) {
+	err := &StoreError{Op: "get", Key: 42, Err: context.DeadlineExceeded}
+	assert.Equal(t, "echo-http-cache: store get 16: context deadline exceeded", err.Error())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
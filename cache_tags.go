@@ -0,0 +1,105 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	// CacheTagStore is implemented by stores supporting tag-based invalidation.
+	CacheTagStore interface {
+		// SetTags associates the tags with a given key until an Expiration date.
+		SetTags(key uint64, tags []string, expiration time.Time)
+
+		// InvalidateTags releases every cached response associated with one of the tags.
+		InvalidateTags(tags ...string) error
+	}
+
+	// tagReleaser is implemented by the tag stores reporting the keys released by InvalidateTags,
+	// so that the two-level store also releases the L1 copies warmed from L2, which L1 did not tag.
+	tagReleaser interface {
+		releaseTags(tags ...string) ([]uint64, error)
+	}
+)
+
+// ErrTagsNotSupported is returned when the store does not support tag-based invalidation.
+var ErrTagsNotSupported = errors.New("store does not support cache tags")
+
+// contextKeyTags is the echo context key of the tags set by SetCacheTags.
+const contextKeyTags = "echo-http-cache.tags"
+
+// SetCacheTags attaches tags to the response of the request, such as "product:42",
+// so that it can be released with InvalidateTags.
+func SetCacheTags(c echo.Context, tags ...string) {
+	current, _ := c.Get(contextKeyTags).([]string)
+	c.Set(contextKeyTags, append(current, tags...))
+}
+
+// InvalidateTags releases every response cached in the store with one of the tags.
+func InvalidateTags(store CacheStore, tags ...string) error {
+	tagStore, ok := StoreAs[CacheTagStore](store)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+	return tagStore.InvalidateTags(tags...)
+}
+
+// responseTags returns the tags of the rule, the tags set by SetCacheTags and the tags
+// listed in the tag header of the response, separated by spaces or commas.
+func responseTags(c echo.Context, ruleTags []string, header http.Header, tagHeader string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	add := func(tag string) {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	contextTags, _ := c.Get(contextKeyTags).([]string)
+	for _, tag := range append(append([]string{}, ruleTags...), contextTags...) {
+		add(strings.TrimSpace(tag))
+	}
+
+	if tagHeader != "" {
+		for _, value := range header.Values(tagHeader) {
+			for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' }) {
+				add(tag)
+			}
+		}
+	}
+	return tags
+}
+
+// tagKey returns the redis key of the set holding the keys associated with the tag.
+func tagKey(tag string) string {
+	return redisNamespace + "tag:" + tag
+}
@@ -0,0 +1,75 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type untaggedStore struct{ CacheStore }
+
+func Test_responseTags(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	SetCacheTags(c, "product:1", " products ")
+	SetCacheTags(c, "product:1")
+
+	header := http.Header{}
+	header.Add("Surrogate-Key", "products, catalog  home")
+
+	assert.Equal(t, []string{"product:1", "products", "catalog", "home"}, responseTags(c, nil, header, "Surrogate-Key"))
+	assert.Equal(t, []string{"catalog", "product:1", "products"}, responseTags(c, []string{"catalog"}, header, ""))
+}
+
+func Test_InvalidateTags_notSupported(t *testing.T) {
+	err := InvalidateTags(untaggedStore{NewCacheMemoryStore()}, "products")
+	assert.ErrorIs(t, err, ErrTagsNotSupported)
+}
+
+func Test_CacheWithConfig_tags(t *testing.T) {
+	store := NewCacheMemoryStore()
+	calls := map[string]int{}
+
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:        store,
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/products", "/home"},
+	}))
+	e.GET("/products/:id", func(c echo.Context) error {
+		calls[c.Path()]++
+		SetCacheTags(c, "product:"+c.Param("id"), "products")
+		return c.String(http.StatusOK, "product "+c.Param("id"))
+	})
+	e.GET("/home", func(c echo.Context) error {
+		calls[c.Path()]++
+		c.Response().Header().Set("Surrogate-Key", "home")
+		return c.String(http.StatusOK, "home")
+	})
+
+	request := func(url string) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	for _, url := range []string{"/products/1", "/products/2", "/home", "/products/1", "/products/2", "/home"} {
+		request(url)
+	}
+	assert.Equal(t, map[string]int{"/products/:id": 2, "/home": 1}, calls)
+
+	assert.NoError(t, InvalidateTags(store, "product:1"))
+	request("/products/1")
+	request("/products/2")
+	assert.Equal(t, map[string]int{"/products/:id": 3, "/home": 1}, calls)
+
+	assert.NoError(t, InvalidateTags(store, "products", "home"))
+	request("/products/1")
+	request("/products/2")
+	request("/home")
+	assert.Equal(t, map[string]int{"/products/:id": 5, "/home": 2}, calls)
+}
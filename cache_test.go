@@ -1,10 +1,13 @@
 package echo_http_cache
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -113,7 +116,7 @@ func Test_CacheWithConfig(t *testing.T) {
 			},
 			wants: wants{
 				code:         http.StatusOK,
-				responseBody: "",
+				responseBody: "test",
 				isCached:     false,
 			},
 		},
@@ -135,8 +138,7 @@ func Test_CacheWithConfig(t *testing.T) {
 			assert.Equal(t, tt.wants.isCached, ok)
 
 			if tt.wants.isCached {
-				var cacheResponse CacheResponse
-				err := json.Unmarshal(cacheResp, &cacheResponse)
+				cacheResponse, err := BinaryCodec{}.Decode(cacheResp)
 				assert.NoError(t, err)
 				assert.Equal(t, "test", string(cacheResponse.Body))
 			}
@@ -144,6 +146,453 @@ func Test_CacheWithConfig(t *testing.T) {
 	}
 }
 
+func Test_CacheWithConfig_statusCode(t *testing.T) {
+	e := echo.New()
+
+	type args struct {
+		statusCode  int
+		cacheConfig CacheConfig
+	}
+
+	type wants struct {
+		isCached   bool
+		expiration time.Duration
+	}
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "replay 201 created",
+			args: args{
+				statusCode: http.StatusCreated,
+				cacheConfig: CacheConfig{
+					Expiration:   5 * time.Second,
+					IncludePaths: []string{"foo.bar"},
+				},
+			},
+			wants: wants{
+				isCached:   true,
+				expiration: 5 * time.Second,
+			},
+		},
+		{
+			name: "replay 301 redirect",
+			args: args{
+				statusCode: http.StatusMovedPermanently,
+				cacheConfig: CacheConfig{
+					Expiration:   5 * time.Second,
+					IncludePaths: []string{"foo.bar"},
+				},
+			},
+			wants: wants{
+				isCached:   true,
+				expiration: 5 * time.Second,
+			},
+		},
+		{
+			name: "404 is not cached by default",
+			args: args{
+				statusCode: http.StatusNotFound,
+				cacheConfig: CacheConfig{
+					Expiration:   5 * time.Second,
+					IncludePaths: []string{"foo.bar"},
+				},
+			},
+			wants: wants{
+				isCached: false,
+			},
+		},
+		{
+			name: "404 is cached with NegativeExpiration",
+			args: args{
+				statusCode: http.StatusNotFound,
+				cacheConfig: CacheConfig{
+					Expiration:           5 * time.Second,
+					IncludePaths:         []string{"foo.bar"},
+					CacheableStatusCodes: []int{http.StatusOK, http.StatusNotFound},
+					NegativeExpiration:   1 * time.Second,
+				},
+			},
+			wants: wants{
+				isCached:   true,
+				expiration: 1 * time.Second,
+			},
+		},
+		{
+			name: "201 is not cached when not listed",
+			args: args{
+				statusCode: http.StatusCreated,
+				cacheConfig: CacheConfig{
+					Expiration:           5 * time.Second,
+					IncludePaths:         []string{"foo.bar"},
+					CacheableStatusCodes: []int{http.StatusOK},
+				},
+			},
+			wants: wants{
+				isCached: false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := func(c echo.Context) error {
+				calls++
+				return c.String(tt.args.statusCode, "test")
+			}
+
+			tt.args.cacheConfig.Store = NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{
+				Capacity:  5,
+				Algorithm: LFU,
+			})
+			mw := CacheWithConfig(tt.args.cacheConfig)
+
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodGet, "http://foo.bar/status", nil)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+
+				_ = mw(handler)(c)
+				assert.Equal(t, tt.args.statusCode, rec.Code)
+				assert.Equal(t, "test", rec.Body.String())
+			}
+
+			if tt.wants.isCached {
+				assert.Equal(t, 1, calls)
+
+				cacheResp, ok := tt.args.cacheConfig.Store.Get(generateKey(http.MethodGet, "http://foo.bar/status"))
+				assert.True(t, ok)
+
+				response := toCacheResponse(cacheResp)
+				assert.Equal(t, tt.args.statusCode, response.StatusCode)
+				assert.WithinDuration(t, time.Now().Add(tt.wants.expiration), response.Expiration, time.Second)
+			} else {
+				assert.Equal(t, 2, calls)
+			}
+		})
+	}
+}
+
+func Test_CacheWithConfig_keyGenerator(t *testing.T) {
+	e := echo.New()
+
+	calls := 0
+	handler := func(c echo.Context) error {
+		calls++
+		return c.String(http.StatusOK, "tenant "+c.Request().Header.Get("X-Tenant"))
+	}
+
+	store := NewCacheMemoryStore()
+	mw := CacheWithConfig(CacheConfig{
+		Store:        store,
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"foo.bar"},
+		KeyGenerator: func(c echo.Context) (string, bool) {
+			tenant := c.Request().Header.Get("X-Tenant")
+			if tenant == "" {
+				return "", false
+			}
+			return tenant + ":" + c.Request().URL.Path, true
+		},
+	})
+
+	tests := []struct {
+		tenant    string
+		wantBody  string
+		wantCalls int
+	}{
+		{"a", "tenant a", 1},
+		{"b", "tenant b", 2},
+		{"a", "tenant a", 2},
+		{"", "tenant ", 3},
+		{"", "tenant ", 4},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/tenant?utm_source=mail", nil)
+		req.Header.Set("X-Tenant", tt.tenant)
+		rec := httptest.NewRecorder()
+		_ = mw(handler)(e.NewContext(req, rec))
+
+		assert.Equal(t, tt.wantBody, rec.Body.String())
+		assert.Equal(t, tt.wantCalls, calls)
+	}
+
+	_, ok := store.Get(hashKey("a:/tenant"))
+	assert.True(t, ok)
+}
+
+func Test_CacheWithConfig_head(t *testing.T) {
+	type wants struct {
+		code      int
+		getCalls  int
+		headCalls int
+	}
+	tests := []struct {
+		name            string
+		cached          bool
+		fillCacheOnHEAD bool
+		wants           wants
+	}{
+		{
+			name:   "head is served from the cached get response",
+			cached: true,
+			wants:  wants{code: http.StatusOK, getCalls: 1},
+		},
+		{
+			name:  "head miss is passed to the next handler",
+			wants: wants{code: http.StatusNoContent, headCalls: 1},
+		},
+		{
+			name:            "head miss fills the cache through the get handler",
+			fillCacheOnHEAD: true,
+			wants:           wants{code: http.StatusOK, getCalls: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getCalls, headCalls := 0, 0
+
+			e := echo.New()
+			e.Use(CacheWithConfig(CacheConfig{
+				Store:           NewCacheMemoryStore(),
+				Expiration:      5 * time.Second,
+				IncludePaths:    []string{"/head"},
+				FillCacheOnHEAD: tt.fillCacheOnHEAD,
+			}))
+			e.GET("/head", func(c echo.Context) error {
+				getCalls++
+				c.Response().Header().Set("X-Test", "get")
+				return c.String(http.StatusOK, "hello")
+			})
+			e.HEAD("/head", func(c echo.Context) error {
+				headCalls++
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			if tt.cached {
+				e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/head", nil))
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/head", nil))
+
+			assert.Equal(t, tt.wants.code, rec.Code)
+			assert.Empty(t, rec.Body.String())
+			assert.Equal(t, tt.wants.getCalls, getCalls)
+			assert.Equal(t, tt.wants.headCalls, headCalls)
+			if tt.wants.getCalls > 0 {
+				assert.Equal(t, "get", rec.Header().Get("X-Test"))
+				assert.Equal(t, "5", rec.Header().Get(echo.HeaderContentLength))
+
+				// the response is cached for the following GET requests
+				rec = httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/head", nil))
+				assert.Equal(t, "hello", rec.Body.String())
+				assert.Equal(t, 1, getCalls)
+			}
+		})
+	}
+}
+
+func Test_CacheWithConfig_staleWhileRevalidate(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:                NewCacheMemoryStore(),
+		Expiration:           100 * time.Millisecond,
+		IncludePaths:         []string{"/swr"},
+		StaleWhileRevalidate: time.Minute,
+	}))
+	e.GET("/swr", func(c echo.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		if n > 1 {
+			<-release
+		}
+		return c.String(http.StatusOK, fmt.Sprintf("response %d", n))
+	})
+
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/swr", nil))
+		return rec
+	}
+
+	assert.Equal(t, "response 1", get().Body.String())
+	time.Sleep(150 * time.Millisecond)
+
+	// expired: the stale response is served and refreshed once in the background
+	for i := 0; i < 3; i++ {
+		rec := get()
+		assert.Equal(t, "response 1", rec.Body.String())
+		assert.Equal(t, `110 - "Response is Stale"`, rec.Header().Get("Warning"))
+	}
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		return get().Body.String() == "response 2"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func Test_CacheWithConfig_staleWhileRevalidate_panic(t *testing.T) {
+	var calls int32
+	errs := make(chan error, 1)
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:                NewCacheMemoryStore(),
+		Expiration:           100 * time.Millisecond,
+		IncludePaths:         []string{"/swr"},
+		StaleWhileRevalidate: time.Minute,
+		ErrorHandler: func(c echo.Context, err error) {
+			errs <- err
+		},
+	}))
+	e.GET("/swr", func(c echo.Context) error {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			panic("boom")
+		}
+		return c.String(http.StatusOK, "response 1")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/swr", nil))
+	time.Sleep(150 * time.Millisecond)
+
+	// the panic of the background refresh is reported instead of crashing the process
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/swr", nil))
+	assert.Equal(t, "response 1", rec.Body.String())
+
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "echo-http-cache: revalidation panic: boom")
+	case <-time.After(time.Second):
+		t.Fatal("the panic was not reported")
+	}
+}
+
+func Test_CacheWithConfig_revalidateTimeout(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	defer close(release)
+	e := echo.New()
+	e.Use(CacheWithConfig(CacheConfig{
+		Store:                NewCacheMemoryStore(),
+		Expiration:           100 * time.Millisecond,
+		IncludePaths:         []string{"/swr"},
+		StaleWhileRevalidate: time.Minute,
+		RevalidateTimeout:    50 * time.Millisecond,
+	}))
+	e.GET("/swr", func(c echo.Context) error {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			<-release
+		}
+		return c.String(http.StatusOK, "response")
+	})
+
+	get := func() {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/swr", nil))
+	}
+
+	get()
+	time.Sleep(150 * time.Millisecond)
+
+	// the hung refresh blocks other refreshes until its deadline expires
+	get()
+	get()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	get()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 3 }, time.Second, 10*time.Millisecond)
+}
+
+func Test_CacheWithConfig_staleIfError(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  echo.HandlerFunc
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "handler error",
+			handler: func(c echo.Context) error {
+				return errors.New("database is down")
+			},
+			wantCode: http.StatusOK,
+			wantBody: "response 1",
+		},
+		{
+			name: "5xx response",
+			handler: func(c echo.Context) error {
+				return c.String(http.StatusBadGateway, "bad gateway")
+			},
+			wantCode: http.StatusOK,
+			wantBody: "response 1",
+		},
+		{
+			name: "handler panic",
+			handler: func(c echo.Context) error {
+				panic("unexpected")
+			},
+			wantCode: http.StatusOK,
+			wantBody: "response 1",
+		},
+		{
+			name: "4xx response is not replaced",
+			handler: func(c echo.Context) error {
+				return c.String(http.StatusNotFound, "not found")
+			},
+			wantCode: http.StatusNotFound,
+			wantBody: "not found",
+		},
+		{
+			name: "successful response is not replaced",
+			handler: func(c echo.Context) error {
+				c.Response().Header().Set("X-Test", "fresh")
+				return c.String(http.StatusOK, "response 2")
+			},
+			wantCode: http.StatusOK,
+			wantBody: "response 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			e := echo.New()
+			e.Use(CacheWithConfig(CacheConfig{
+				Store:        NewCacheMemoryStore(),
+				Expiration:   50 * time.Millisecond,
+				IncludePaths: []string{"/sie"},
+				StaleIfError: time.Minute,
+			}))
+			e.GET("/sie", func(c echo.Context) error {
+				calls++
+				if calls == 1 {
+					return c.String(http.StatusOK, "response 1")
+				}
+				return tt.handler(c)
+			})
+
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sie", nil))
+			time.Sleep(100 * time.Millisecond)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sie", nil))
+
+			assert.Equal(t, 2, calls)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, rec.Body.String())
+			if tt.wantBody == "response 1" {
+				assert.Equal(t, `111 - "Revalidation Failed"`, rec.Header().Get("Warning"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Warning"))
+			}
+		})
+	}
+}
+
 func TestCache_panicBehavior(t *testing.T) {
 	inMemoryStore := NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{
 		Capacity:  5,
@@ -284,57 +733,91 @@ func Test_sortURLParams(t *testing.T) {
 	}
 }
 
-func Test_isAllFieldsEmpty(t *testing.T) {
-	type person struct {
-		Name    string `json:"name"`
-		Age     int    `json:"age"`
-		Address struct {
-			City string `json:"city"`
-			Zip  int    `json:"zip"`
-		} `json:"address"`
-		UpdatedAt time.Time `json:"updatedAt"`
-	}
-
-	p1 := person{
-		Address: struct {
-			City string `json:"city"`
-			Zip  int    `json:"zip"`
-		}{
-			City: "seoul",
-		},
-	}
+// writeHeaderCounter counts the WriteHeader calls of the response writer.
+type writeHeaderCounter struct {
+	*httptest.ResponseRecorder
+	calls int
+}
 
-	p2 := person{}
-	p3 := person{
-		Name: "",
-		Age:  0,
-		Address: struct {
-			City string `json:"city"`
-			Zip  int    `json:"zip"`
-		}{
-			City: "",
-			Zip:  0,
-		},
-	}
+func (w *writeHeaderCounter) WriteHeader(code int) {
+	w.calls++
+	w.ResponseRecorder.WriteHeader(code)
+}
+
+func Test_bodyDumpResponseWriter_bufferedEventStream(t *testing.T) {
+	rec := &writeHeaderCounter{ResponseRecorder: httptest.NewRecorder()}
+	writer := newBodyDumpResponseWriter(rec, true, 0)
 
-	assert.False(t, isAllFieldsEmpty(p1))
-	assert.True(t, isAllFieldsEmpty(p2))
-	assert.True(t, isAllFieldsEmpty(p3))
-	assert.False(t, isAllFieldsEmpty([]byte(`{"a":"","b":"","c":1}`)))
-	assert.False(t, isAllFieldsEmpty([]byte(`{"a":"","b":"b","c":0}`)))
-	assert.True(t, isAllFieldsEmpty([]byte(`{"a":"","b":"","c":0}`)))
-	assert.True(t, isAllFieldsEmpty([]byte(`{"a":"","b":"","c":0.0}`)))
-	assert.True(t, isAllFieldsEmpty([]byte(`{"a":"","b":"","c":0.0,"updatedAt":"0001-01-01T00:00:00Z"}`)))
+	writer.Header().Set(echo.HeaderContentType, "text/event-stream")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write([]byte("data: 1\n\n"))
+
+	assert.Equal(t, 1, rec.calls)
+	assert.True(t, writer.uncacheable)
+	assert.Equal(t, "data: 1\n\n", rec.Body.String())
 }
 
-func Test_isIncludePaths(t *testing.T) {
-	config := CacheConfig{
-		IncludePathsWithExpiration: map[string]time.Duration{
-			"/test1": time.Duration(1) * time.Second,
-			"/test2": time.Duration(2) * time.Second,
+func Test_CacheWithConfig_uncacheableResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler echo.HandlerFunc
+		want    string
+	}{
+		{
+			name: "body larger than MaxBodySize",
+			handler: func(c echo.Context) error {
+				return c.String(http.StatusOK, strings.Repeat("a", 20))
+			},
+			want: strings.Repeat("a", 20),
+		},
+		{
+			name: "body written in chunks larger than MaxBodySize",
+			handler: func(c echo.Context) error {
+				c.Response().WriteHeader(http.StatusOK)
+				for i := 0; i < 4; i++ {
+					_, _ = c.Response().Write([]byte("chunk"))
+				}
+				return nil
+			},
+			want: strings.Repeat("chunk", 4),
 		},
+		{
+			name: "flushed response",
+			handler: func(c echo.Context) error {
+				c.Response().WriteHeader(http.StatusOK)
+				_, _ = c.Response().Write([]byte("part1"))
+				c.Response().Flush()
+				_, _ = c.Response().Write([]byte("part2"))
+				return nil
+			},
+			want: "part1part2",
+		},
+		{
+			name: "event stream",
+			handler: func(c echo.Context) error {
+				return c.Blob(http.StatusOK, "text/event-stream; charset=utf-8", []byte("data: 1\n\n"))
+			},
+			want: "data: 1\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewCacheMemoryStore()
+			mw := CacheWithConfig(CacheConfig{
+				Store:        store,
+				Expiration:   5 * time.Second,
+				IncludePaths: []string{"/"},
+				MaxBodySize:  16,
+				StaleIfError: time.Minute,
+			})
+
+			rec := httptest.NewRecorder()
+			_ = mw(tt.handler)(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.want, rec.Body.String())
+			_, ok := store.Get(generateKey(http.MethodGet, "/"))
+			assert.False(t, ok)
+		})
 	}
-	assert.False(t, config.isIncludePaths("/test3"))
-	assert.True(t, config.isIncludePaths("/test1"))
-	assert.True(t, config.isIncludePaths("/test2"))
 }
@@ -0,0 +1,75 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import "context"
+
+type (
+	// CacheTracer traces the lookups and the fills of the middleware. The context of the
+	// request is the parent of the spans, whose context is passed to the store calls and
+	// to the handler. The opentelemetry subpackage implements it with OpenTelemetry.
+	CacheTracer interface {
+		// Start starts a span named name, child of the span of ctx, and returns its context.
+		Start(ctx context.Context, name string) (context.Context, CacheSpan)
+	}
+
+	// CacheSpan is a span started by a CacheTracer.
+	CacheSpan interface {
+		// SetAttribute sets an attribute of the span, with a string, int, int64 or bool value.
+		SetAttribute(key string, value any)
+
+		// End ends the span, recording err when it is not nil.
+		End(err error)
+	}
+)
+
+// Span names.
+const (
+	SpanLookup = "echo-http-cache.lookup"
+	SpanFill   = "echo-http-cache.fill"
+)
+
+// Span attribute keys.
+const (
+	AttributeCacheStatus    = "cache.status"
+	AttributeCacheKey       = "cache.key"
+	AttributeCacheLevel     = "cache.level"
+	AttributeCacheEntrySize = "cache.entry_size"
+	AttributeCacheStored    = "cache.stored"
+	AttributeStatusCode     = "http.response.status_code"
+)
+
+// noopTracer is the tracer of the middleware when CacheConfig.Tracer is not set.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string) (context.Context, CacheSpan) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+
+func (noopSpan) End(error) {}
@@ -0,0 +1,119 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package echo_http_cache
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A response with a Vary header is stored in two parts. The primary key holds a vary
+// marker, a CacheResponse with only VaryHeaders set, and every variant is stored under
+// a key derived from the primary key and the normalized values of the request headers
+// named by the marker.
+
+// parseVary returns the sorted, canonical header names of the Vary header.
+// It returns "*" as the only name when the response varies on everything.
+func parseVary(header http.Header) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == "*" {
+				return []string{"*"}
+			}
+
+			name = http.CanonicalHeaderKey(name)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// variantHeaders returns the names of the Vary header that select a variant of the stored
+// response. Accept-Encoding is ignored when the stored body is not encoded, since the body
+// is the same for every request and the encoding is negotiated when it is served.
+func variantHeaders(header http.Header) []string {
+	names := parseVary(header)
+	if header.Get("Content-Encoding") != "" {
+		return names
+	}
+
+	variant := names[:0:0]
+	for _, name := range names {
+		if name != "Accept-Encoding" {
+			variant = append(variant, name)
+		}
+	}
+	return variant
+}
+
+// isVaryAll reports whether the Vary header names contain "*".
+func isVaryAll(names []string) bool {
+	return len(names) == 1 && names[0] == "*"
+}
+
+// varyKey returns the key of the variant selected by the request header values.
+func varyKey(key uint64, names []string, header http.Header) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(keyAsString(key)))
+	for _, name := range names {
+		hash.Write([]byte("\n" + name + ":" + normalizeVaryValue(header.Values(name))))
+	}
+
+	return hash.Sum64()
+}
+
+// normalizeVaryValue lower-cases the comma separated elements of the header values,
+// removes whitespace around them and sorts them, so that equivalent requests select
+// the same variant.
+func normalizeVaryValue(values []string) string {
+	var elements []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			element = strings.ToLower(strings.Join(strings.Fields(element), ""))
+			if element != "" {
+				elements = append(elements, element)
+			}
+		}
+	}
+	sort.Strings(elements)
+	return strings.Join(elements, ",")
+}
+
+// isVaryMarker reports whether the cached response is a vary marker rather than a response.
+func (r CacheResponse) isVaryMarker() bool {
+	return len(r.VaryHeaders) > 0
+}
@@ -0,0 +1,129 @@
+package echo_http_cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseVary(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   []string
+	}{
+		{
+			name:   "no vary",
+			header: http.Header{},
+			want:   nil,
+		},
+		{
+			name:   "sorted and canonical",
+			header: http.Header{"Vary": {"accept-language, Accept", "ACCEPT"}},
+			want:   []string{"Accept", "Accept-Language"},
+		},
+		{
+			name:   "vary all",
+			header: http.Header{"Vary": {"Accept, *"}},
+			want:   []string{"*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseVary(tt.header))
+		})
+	}
+}
+
+func Test_varyKey(t *testing.T) {
+	names := []string{"Accept-Encoding"}
+
+	key1 := varyKey(1, names, http.Header{"Accept-Encoding": {"gzip, br"}})
+	key2 := varyKey(1, names, http.Header{"Accept-Encoding": {"BR,gzip"}})
+	key3 := varyKey(1, names, http.Header{"Accept-Encoding": {"gzip"}})
+	key4 := varyKey(2, names, http.Header{"Accept-Encoding": {"gzip, br"}})
+
+	assert.Equal(t, key1, key2)
+	assert.NotEqual(t, key1, key3)
+	assert.NotEqual(t, key1, key4)
+}
+
+func Test_CacheWithConfig_vary(t *testing.T) {
+	e := echo.New()
+
+	calls := 0
+	handler := func(c echo.Context) error {
+		calls++
+		c.Response().Header().Set("Vary", "Accept")
+		if c.Request().Header.Get("Accept") == "text/csv" {
+			return c.String(http.StatusOK, "id,name")
+		}
+		return c.JSON(http.StatusOK, map[string]string{"id": "1"})
+	}
+
+	mw := CacheWithConfig(CacheConfig{
+		Store:        NewCacheMemoryStore(),
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"foo.bar"},
+	})
+
+	tests := []struct {
+		accept    string
+		wantBody  string
+		wantCalls int
+	}{
+		{"application/json", "{\"id\":\"1\"}\n", 1},
+		{"text/csv", "id,name", 2},
+		{"application/json", "{\"id\":\"1\"}\n", 2},
+		{"text/csv", "id,name", 2},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/vary", nil)
+		req.Header.Set("Accept", tt.accept)
+		rec := httptest.NewRecorder()
+		_ = mw(handler)(e.NewContext(req, rec))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, tt.wantBody, rec.Body.String())
+		assert.Equal(t, tt.wantCalls, calls)
+	}
+}
+
+func Test_CacheWithConfig_varyAll(t *testing.T) {
+	e := echo.New()
+
+	calls := 0
+	handler := func(c echo.Context) error {
+		calls++
+		c.Response().Header().Set("Vary", "*")
+		return c.String(http.StatusOK, "test")
+	}
+
+	store := NewCacheMemoryStore()
+	mw := CacheWithConfig(CacheConfig{
+		Store:        store,
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"foo.bar"},
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://foo.bar/vary-all", nil)
+		_ = mw(handler)(e.NewContext(req, httptest.NewRecorder()))
+	}
+
+	assert.Equal(t, 2, calls)
+	_, ok := store.Get(generateKey(http.MethodGet, "http://foo.bar/vary-all"))
+	assert.False(t, ok)
+}
+
+func Test_variantHeaders(t *testing.T) {
+	header := http.Header{"Vary": {"Accept-Encoding, Accept-Language"}}
+	assert.Equal(t, []string{"Accept-Language"}, variantHeaders(header))
+
+	header.Set("Content-Encoding", "gzip")
+	assert.Equal(t, []string{"Accept-Encoding", "Accept-Language"}, variantHeaders(header))
+}
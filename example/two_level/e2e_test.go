@@ -111,11 +111,12 @@ func getCacheStats(t *testing.T) *echo_http_cache.CacheStats {
 		return nil
 	}
 
-	var stats echo_http_cache.CacheStats
-	if err := json.Unmarshal(body, &stats); err != nil {
+	var adminStats echo_http_cache.AdminStats
+	if err := json.Unmarshal(body, &adminStats); err != nil || adminStats.Store == nil {
 		t.Errorf("/cache/stats JSON 파싱 실패: %v, 응답: %s", err, string(body))
 		return nil
 	}
+	stats := *adminStats.Store
 
 	t.Logf("캐시 통계: L1Hits=%d, L2Hits=%d, TotalMiss=%d, TotalRequest=%d, HitRate=%.2f%%, L1Size=%d, L2Size=%d",
 		stats.L1Hits, stats.L2Hits, stats.TotalMiss, stats.TotalRequest, stats.HitRate, stats.L1Size, stats.L2Size)
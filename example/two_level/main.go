@@ -70,51 +70,11 @@ func main() {
 		})
 	})
 
-	// Cache management endpoints
-	if twoLevel, ok := twoLevelStore.(*echo_http_cache.CacheTwoLevelStore); ok {
-		// Get cache statistics
-		e.GET("/cache/stats", func(c echo.Context) error {
-			stats := twoLevel.GetStats()
-			return c.JSON(http.StatusOK, stats)
-		})
-
-		// Clear L1 cache only
-		e.DELETE("/cache/l1", func(c echo.Context) error {
-			if err := twoLevel.ClearL1(); err != nil {
-				return c.JSON(http.StatusInternalServerError, map[string]string{
-					"error": err.Error(),
-				})
-			}
-			return c.JSON(http.StatusOK, map[string]string{
-				"message": "L1 cache cleared successfully",
-			})
-		})
+	// Cache management endpoints: stats, entry lookup, key listing, purge and clear
+	echo_http_cache.CacheAdmin(e.Group("/cache"), twoLevelStore)
 
-		// Clear L2 cache only
-		e.DELETE("/cache/l2", func(c echo.Context) error {
-			if err := twoLevel.ClearL2(); err != nil {
-				return c.JSON(http.StatusInternalServerError, map[string]string{
-					"error": err.Error(),
-				})
-			}
-			return c.JSON(http.StatusOK, map[string]string{
-				"message": "L2 cache cleared successfully",
-			})
-		})
-
-		// Clear all caches
-		e.DELETE("/cache/all", func(c echo.Context) error {
-			if err := twoLevel.ClearAll(); err != nil {
-				return c.JSON(http.StatusInternalServerError, map[string]string{
-					"error": err.Error(),
-				})
-			}
-			return c.JSON(http.StatusOK, map[string]string{
-				"message": "All caches cleared successfully",
-			})
-		})
-
-		// Reset statistics
+	// Reset statistics
+	if twoLevel, ok := twoLevelStore.(*echo_http_cache.CacheTwoLevelStore); ok {
 		e.POST("/cache/stats/reset", func(c echo.Context) error {
 			twoLevel.ResetStats()
 			return c.JSON(http.StatusOK, map[string]string{
@@ -136,9 +96,13 @@ func main() {
 	log.Println("  GET  /api/data        - Cached API endpoint")
 	log.Println("  GET  /api/user/:id    - User data endpoint")
 	log.Println("  GET  /cache/stats     - Cache statistics")
-	log.Println("  DELETE /cache/l1      - Clear L1 cache")
-	log.Println("  DELETE /cache/l2      - Clear L2 cache")
-	log.Println("  DELETE /cache/all     - Clear all caches")
+	log.Println("  GET  /cache/keys      - Cached keys")
+	log.Println("  GET  /cache/entry?url=/api/data - Cached response")
+	log.Println("  DELETE /cache/entry?url=/api/data - Purge a cached response")
+	log.Println("  DELETE /cache/tags?tag=products - Purge by tag")
+	log.Println("  DELETE /cache/levels/l1 - Clear L1 cache")
+	log.Println("  DELETE /cache/levels/l2 - Clear L2 cache")
+	log.Println("  DELETE /cache/levels/all - Clear all caches")
 	log.Println("  POST /cache/stats/reset - Reset statistics")
 	log.Println("  GET  /health          - Health check")
 
@@ -25,7 +25,13 @@ SOFTWARE.
 package echo_http_cache
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -53,6 +59,14 @@ type (
 		capacity  int
 		algorithm Algorithm
 		store     map[uint64][]byte
+
+		// tags holds the keys associated with each tag and keyTags the tags of each key,
+		// so that InvalidateTags does not decode the stored responses
+		tags    map[string]map[uint64]struct{}
+		keyTags map[uint64][]string
+
+		// evictions is the number of responses evicted to make room for new ones
+		evictions int64
 	}
 )
 
@@ -76,6 +90,8 @@ func NewCacheMemoryStoreWithConfig(config CacheMemoryStoreConfig) (store *CacheM
 	}
 	store.mutex = sync.RWMutex{}
 	store.store = make(map[uint64][]byte, store.capacity)
+	store.tags = make(map[string]map[uint64]struct{})
+	store.keyTags = make(map[uint64][]string)
 	return store
 }
 
@@ -107,9 +123,11 @@ func (store *CacheMemoryStore) Get(key uint64) ([]byte, bool) {
 func (store *CacheMemoryStore) Set(key uint64, response []byte, _ time.Time) {
 	store.mutex.RLock()
 	length := len(store.store)
+	_, exists := store.store[key]
 	store.mutex.RUnlock()
 
-	if length > 0 && length == store.capacity {
+	// replacing a response does not need room
+	if !exists && length > 0 && length == store.capacity {
 		store.evict()
 	}
 
@@ -127,6 +145,7 @@ func (store *CacheMemoryStore) Release(key uint64) {
 	if ok {
 		store.mutex.Lock()
 		delete(store.store, key)
+		store.untag(key)
 		store.mutex.Unlock()
 	}
 }
@@ -170,6 +189,12 @@ func (store *CacheMemoryStore) evict() {
 	}
 
 	store.Release(selectedKey)
+	atomic.AddInt64(&store.evictions, 1)
+}
+
+// Evictions returns the number of responses evicted since the store was created.
+func (store *CacheMemoryStore) Evictions(context.Context) (int64, error) {
+	return atomic.LoadInt64(&store.evictions), nil
 }
 
 // Clear removes all entries from the memory store
@@ -179,5 +204,170 @@ func (store *CacheMemoryStore) Clear() error {
 
 	// Clear the entire map
 	store.store = make(map[uint64][]byte, store.capacity)
+	store.tags = make(map[string]map[uint64]struct{})
+	store.keyTags = make(map[uint64][]string)
 	return nil
 }
+
+// SetTags implements the CacheTagStore interface SetTags method. The tags replace the
+// ones previously associated with the key, until the key is released.
+func (store *CacheMemoryStore) SetTags(key uint64, tags []string, _ time.Time) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.untag(key)
+	for _, tag := range tags {
+		keys, ok := store.tags[tag]
+		if !ok {
+			keys = make(map[uint64]struct{})
+			store.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	if len(tags) > 0 {
+		store.keyTags[key] = append([]string(nil), tags...)
+	}
+}
+
+// InvalidateTags implements the CacheTagStore interface InvalidateTags method.
+func (store *CacheMemoryStore) InvalidateTags(tags ...string) error {
+	_, err := store.releaseTags(tags...)
+	return err
+}
+
+func (store *CacheMemoryStore) releaseTags(tags ...string) ([]uint64, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var released []uint64
+	for _, tag := range tags {
+		for key := range store.tags[tag] {
+			delete(store.store, key)
+			store.untag(key)
+			released = append(released, key)
+		}
+	}
+	return released, nil
+}
+
+// untag removes the key from the sets of its tags. The caller holds the write lock.
+func (store *CacheMemoryStore) untag(key uint64) {
+	for _, tag := range store.keyTags[key] {
+		delete(store.tags[tag], key)
+		if len(store.tags[tag]) == 0 {
+			delete(store.tags, tag)
+		}
+	}
+	delete(store.keyTags, key)
+}
+
+// GetContext implements the CacheStoreV2 interface GetContext method.
+func (store *CacheMemoryStore) GetContext(_ context.Context, key uint64) ([]byte, bool, error) {
+	response, ok := store.Get(key)
+	return response, ok, nil
+}
+
+// GetWithTTL implements the CacheStoreV2 interface GetWithTTL method. The time to live
+// is the one of the stored response, since the store itself does not expire keys.
+func (store *CacheMemoryStore) GetWithTTL(_ context.Context, key uint64) ([]byte, time.Duration, bool, error) {
+	response, ok := store.Get(key)
+	if !ok {
+		return nil, 0, false, nil
+	}
+
+	var ttl time.Duration
+	if expiration := toCacheResponse(response).Expiration; !expiration.IsZero() {
+		ttl = time.Until(expiration)
+	}
+	return response, ttl, true, nil
+}
+
+// SetContext implements the CacheStoreV2 interface SetContext method.
+func (store *CacheMemoryStore) SetContext(_ context.Context, key uint64, response []byte, expiration time.Time) error {
+	store.Set(key, response, expiration)
+	return nil
+}
+
+// Delete implements the CacheStoreV2 interface Delete method.
+func (store *CacheMemoryStore) Delete(_ context.Context, key uint64) error {
+	store.Release(key)
+	return nil
+}
+
+// Exists implements the CacheStoreV2 interface Exists method.
+func (store *CacheMemoryStore) Exists(_ context.Context, key uint64) (bool, error) {
+	_, ok := store.Get(key)
+	return ok, nil
+}
+
+// Size returns the number of cached responses.
+func (store *CacheMemoryStore) Size() int {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	return len(store.store)
+}
+
+// Keys implements the CacheKeyLister interface Keys method. The keys are listed in
+// ascending order, the cursor being the last key of the previous page.
+func (store *CacheMemoryStore) Keys(_ context.Context, cursor string, limit int) ([]uint64, string, error) {
+	var after uint64
+	if cursor != "" {
+		var err error
+		if after, err = strconv.ParseUint(cursor, 36, 64); err != nil {
+			return nil, "", fmt.Errorf("invalid cursor %q", cursor)
+		}
+	}
+
+	store.mutex.RLock()
+	keys := make([]uint64, 0, len(store.store))
+	for key := range store.store {
+		if cursor == "" || key > after {
+			keys = append(keys, key)
+		}
+	}
+	store.mutex.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	if len(keys) <= limit {
+		return keys, "", nil
+	}
+	keys = keys[:limit]
+	return keys, keyAsString(keys[limit-1]), nil
+}
+
+// PurgePrefix implements the CachePrefixPurger interface PurgePrefix method, releasing the
+// keys indexed under the path tags given to SetTags.
+func (store *CacheMemoryStore) PurgePrefix(ctx context.Context, prefix string) (int, error) {
+	_, purged, err := store.releasePaths(ctx, prefix, prefixMatcher(prefix))
+	return purged, err
+}
+
+// PurgePattern implements the CachePatternPurger interface PurgePattern method, releasing the
+// keys indexed under the path tags given to SetTags.
+func (store *CacheMemoryStore) PurgePattern(ctx context.Context, pattern string) (int, error) {
+	_, purged, err := store.releasePaths(ctx, globLiteralPrefix(pattern), patternMatcher(pattern))
+	return purged, err
+}
+
+// releasePaths releases the responses of the path tags whose path matches.
+func (store *CacheMemoryStore) releasePaths(_ context.Context, _ string, match func(path string) bool) ([]uint64, int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var released []uint64
+	purged := 0
+	for tag, keys := range store.tags {
+		if path, ok := strings.CutPrefix(tag, pathTagPrefix); !ok || !match(path) {
+			continue
+		}
+		for key := range keys {
+			if _, ok := store.store[key]; ok {
+				delete(store.store, key)
+				purged++
+			}
+			store.untag(key)
+			released = append(released, key)
+		}
+	}
+	return released, purged, nil
+}
@@ -1,6 +1,7 @@
 package echo_http_cache
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -10,10 +11,10 @@ import (
 
 func TestGet(t *testing.T) {
 	store := &CacheMemoryStore{
-		sync.RWMutex{},
-		2,
-		LRU,
-		map[uint64][]byte{
+		mutex:     sync.RWMutex{},
+		capacity:  2,
+		algorithm: LRU,
+		store: map[uint64][]byte{
 			14974843192121052621: CacheResponse{
 				Body:       []byte("value 1"),
 				Expiration: time.Now(),
@@ -55,10 +56,10 @@ func TestGet(t *testing.T) {
 
 func TestSet(t *testing.T) {
 	store := &CacheMemoryStore{
-		sync.RWMutex{},
-		2,
-		LRU,
-		make(map[uint64][]byte),
+		mutex:     sync.RWMutex{},
+		capacity:  2,
+		algorithm: LRU,
+		store:     make(map[uint64][]byte),
 	}
 
 	tests := []struct {
@@ -105,10 +106,10 @@ func TestSet(t *testing.T) {
 
 func TestRelease(t *testing.T) {
 	store := &CacheMemoryStore{
-		sync.RWMutex{},
-		2,
-		LRU,
-		map[uint64][]byte{
+		mutex:     sync.RWMutex{},
+		capacity:  2,
+		algorithm: LRU,
+		store: map[uint64][]byte{
 			14974843192121052621: CacheResponse{
 				Expiration: time.Now().Add(1 * time.Minute),
 				Body:       []byte("value 1"),
@@ -180,10 +181,10 @@ func TestEvict(t *testing.T) {
 		count++
 
 		store := &CacheMemoryStore{
-			sync.RWMutex{},
-			2,
-			tt.algorithm,
-			map[uint64][]byte{
+			mutex:     sync.RWMutex{},
+			capacity:  2,
+			algorithm: tt.algorithm,
+			store: map[uint64][]byte{
 				14974843192121052621: CacheResponse{
 					Body:       []byte("value 1"),
 					Expiration: time.Now().Add(1 * time.Minute),
@@ -232,3 +233,59 @@ func TestEvict(t *testing.T) {
 		})
 	}
 }
+
+func TestEvictions(t *testing.T) {
+	store := NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{
+		Capacity:  2,
+		Algorithm: LRU,
+	})
+	expiration := time.Now().Add(time.Minute)
+
+	store.Set(1, CacheResponse{Body: []byte("value 1"), LastAccess: time.Now()}.bytes(), expiration)
+	store.Set(2, CacheResponse{Body: []byte("value 2"), LastAccess: time.Now()}.bytes(), expiration)
+
+	// replacing a cached response does not evict another one
+	store.Set(2, CacheResponse{Body: []byte("value 2"), LastAccess: time.Now()}.bytes(), expiration)
+	evictions, err := store.Evictions(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), evictions)
+	assert.Len(t, store.store, 2)
+
+	store.Set(3, CacheResponse{Body: []byte("value 3"), LastAccess: time.Now()}.bytes(), expiration)
+	evictions, err = store.Evictions(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), evictions)
+	assert.Len(t, store.store, 2)
+}
+
+func TestInvalidateTags(t *testing.T) {
+	store := NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{Capacity: 10})
+	expiration := time.Now().Add(time.Minute)
+	for key := uint64(1); key <= 3; key++ {
+		store.Set(key, CacheResponse{Body: []byte("test")}.bytes(), expiration)
+	}
+	store.SetTags(1, []string{"product:1", "products"}, expiration)
+	store.SetTags(2, []string{"products"}, expiration)
+
+	assert.NoError(t, store.InvalidateTags("product:1"))
+	assert.Equal(t, 2, store.Size())
+	assert.Equal(t, map[string]map[uint64]struct{}{"products": {2: {}}}, store.tags)
+
+	// a released key leaves the index
+	store.Release(2)
+	assert.Empty(t, store.tags)
+	assert.Empty(t, store.keyTags)
+
+	assert.NoError(t, store.InvalidateTags("products"))
+	_, ok := store.Get(3)
+	assert.True(t, ok)
+
+	// new tags replace the previous ones
+	store.SetTags(3, []string{"home"}, expiration)
+	store.SetTags(3, []string{"products"}, expiration)
+	assert.NoError(t, store.InvalidateTags("home"))
+	assert.Equal(t, 1, store.Size())
+
+	assert.NoError(t, store.Clear())
+	assert.Empty(t, store.tags)
+}
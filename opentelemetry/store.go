@@ -0,0 +1,136 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package opentelemetry
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	cache "github.com/kenshin579/echo-http-cache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attribute keys of the store calls.
+const (
+	AttributeStore     = "cache.store"
+	AttributeOperation = "cache.operation"
+)
+
+// InstrumentStore returns the store tracing its calls with the name, such as "L1" and "L2"
+// for the levels of a CacheTwoLevelStore. The spans are children of the span of the
+// context passed to the store.
+func InstrumentStore(name string, store cache.CacheStore, options ...Option) cache.CacheStore {
+	return &instrumentedStore{
+		name:   name,
+		store:  store,
+		v2:     cache.ToCacheStoreV2(store),
+		tracer: newTracer(options),
+	}
+}
+
+// instrumentedStore traces the calls of a store.
+type instrumentedStore struct {
+	name   string
+	store  cache.CacheStore
+	v2     cache.CacheStoreV2
+	tracer trace.Tracer
+}
+
+// start starts the span of an operation on the key.
+func (s *instrumentedStore) start(ctx context.Context, op string, key uint64) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "echo-http-cache.store."+op, trace.WithAttributes(
+		attribute.String(AttributeStore, s.name),
+		attribute.String(AttributeOperation, op),
+		attribute.String(cache.AttributeCacheKey, strconv.FormatUint(key, 36)),
+	))
+}
+
+// endGet ends the span of a read, with its status and entry size.
+func endGet(span trace.Span, data []byte, ok bool, err error) {
+	if ok {
+		span.SetAttributes(
+			attribute.String(cache.AttributeCacheStatus, "hit"),
+			attribute.Int(cache.AttributeCacheEntrySize, len(data)))
+	} else {
+		span.SetAttributes(attribute.String(cache.AttributeCacheStatus, "miss"))
+	}
+	endSpan(span, err)
+}
+
+func (s *instrumentedStore) Get(key uint64) ([]byte, bool) {
+	data, ok, err := s.GetContext(context.Background(), key)
+	return data, ok && err == nil
+}
+
+func (s *instrumentedStore) Set(key uint64, response []byte, expiration time.Time) {
+	_ = s.SetContext(context.Background(), key, response, expiration)
+}
+
+func (s *instrumentedStore) Release(key uint64) {
+	_ = s.Delete(context.Background(), key)
+}
+
+func (s *instrumentedStore) GetContext(ctx context.Context, key uint64) ([]byte, bool, error) {
+	ctx, span := s.start(ctx, "get", key)
+	data, ok, err := s.v2.GetContext(ctx, key)
+	endGet(span, data, ok, err)
+	return data, ok, err
+}
+
+func (s *instrumentedStore) GetWithTTL(ctx context.Context, key uint64) ([]byte, time.Duration, bool, error) {
+	ctx, span := s.start(ctx, "get", key)
+	data, ttl, ok, err := s.v2.GetWithTTL(ctx, key)
+	endGet(span, data, ok, err)
+	return data, ttl, ok, err
+}
+
+func (s *instrumentedStore) SetContext(ctx context.Context, key uint64, response []byte, expiration time.Time) error {
+	ctx, span := s.start(ctx, "set", key)
+	span.SetAttributes(attribute.Int(cache.AttributeCacheEntrySize, len(response)))
+	err := s.v2.SetContext(ctx, key, response, expiration)
+	endSpan(span, err)
+	return err
+}
+
+func (s *instrumentedStore) Delete(ctx context.Context, key uint64) error {
+	ctx, span := s.start(ctx, "delete", key)
+	err := s.v2.Delete(ctx, key)
+	endSpan(span, err)
+	return err
+}
+
+func (s *instrumentedStore) Exists(ctx context.Context, key uint64) (bool, error) {
+	ctx, span := s.start(ctx, "exists", key)
+	ok, err := s.v2.Exists(ctx, key)
+	endSpan(span, err)
+	return ok, err
+}
+
+// Unwrap implements the CacheStoreWrapper interface, returning the traced store.
+func (s *instrumentedStore) Unwrap() cache.CacheStore {
+	return s.store
+}
@@ -0,0 +1,130 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+/*
+Package opentelemetry traces the cache middleware and its stores with OpenTelemetry.
+
+	tracer := opentelemetry.NewTracer()
+	l1 := opentelemetry.InstrumentStore("L1", echo_http_cache.NewCacheMemoryStore())
+	l2 := opentelemetry.InstrumentStore("L2", echo_http_cache.NewCacheRedisStoreWithConfig(options))
+
+	e.Use(echo_http_cache.CacheWithConfig(echo_http_cache.CacheConfig{
+		Store:      echo_http_cache.NewCacheTwoLevelStore(l1, l2),
+		Expiration: 5 * time.Minute,
+		Tracer:     tracer,
+	}))
+
+The spans of the middleware are children of the span of the request context, such as the
+span started by the otelecho middleware, and the spans of the stores are children of the
+lookup and fill spans.
+*/
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+
+	cache "github.com/kenshin579/echo-http-cache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "github.com/kenshin579/echo-http-cache/opentelemetry"
+
+type config struct {
+	provider trace.TracerProvider
+}
+
+// Option configures the tracer of NewTracer and InstrumentStore.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider. Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+func newTracer(options []Option) trace.Tracer {
+	c := config{}
+	for _, option := range options {
+		option(&c)
+	}
+	if c.provider == nil {
+		c.provider = otel.GetTracerProvider()
+	}
+	return c.provider.Tracer(ScopeName)
+}
+
+// NewTracer returns the CacheTracer tracing the lookups and fills of the middleware.
+func NewTracer(options ...Option) cache.CacheTracer {
+	return &tracer{tracer: newTracer(options)}
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, cache.CacheSpan) {
+	ctx, s := t.tracer.Start(ctx, name)
+	return ctx, span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttribute(key string, value any) {
+	s.span.SetAttributes(keyValue(key, value))
+}
+
+func (s span) End(err error) {
+	endSpan(s.span, err)
+}
+
+// endSpan ends the span, recording err when it is not nil.
+func endSpan(s trace.Span, err error) {
+	if err != nil {
+		s.RecordError(err)
+		s.SetStatus(codes.Error, err.Error())
+	}
+	s.End()
+}
+
+func keyValue(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	}
+	return attribute.String(key, fmt.Sprint(value))
+}
@@ -0,0 +1,144 @@
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cache "github.com/kenshin579/echo-http-cache"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttributes returns the attributes of the span as a map.
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestNewTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	e := echo.New()
+	e.Use(cache.CacheWithConfig(cache.CacheConfig{
+		Store:        InstrumentStore("memory", cache.NewCacheMemoryStore(), WithTracerProvider(provider)),
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/test"},
+		Tracer:       NewTracer(WithTracerProvider(provider)),
+	}))
+	e.GET("/test", func(c echo.Context) error {
+		// the handler runs in the fill span
+		_, span := provider.Tracer("handler").Start(c.Request().Context(), "handler")
+		span.End()
+		return c.String(http.StatusOK, "test")
+	})
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	for i := 0; i < 2; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil).WithContext(ctx))
+	}
+	parent.End()
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+
+	lookups := spans[cache.SpanLookup]
+	if assert.Len(t, lookups, 2) {
+		assert.Equal(t, parent.SpanContext().SpanID(), lookups[0].Parent().SpanID())
+		assert.Equal(t, "miss", spanAttributes(lookups[0])[cache.AttributeCacheStatus].AsString())
+		assert.Equal(t, "hit", spanAttributes(lookups[1])[cache.AttributeCacheStatus].AsString())
+		assert.Equal(t, int64(4), spanAttributes(lookups[1])[cache.AttributeCacheEntrySize].AsInt64())
+	}
+
+	fills := spans[cache.SpanFill]
+	if assert.Len(t, fills, 1) {
+		fill := fills[0]
+		assert.Equal(t, parent.SpanContext().SpanID(), fill.Parent().SpanID())
+		assert.Equal(t, int64(http.StatusOK), spanAttributes(fill)[cache.AttributeStatusCode].AsInt64())
+		assert.True(t, spanAttributes(fill)[cache.AttributeCacheStored].AsBool())
+
+		if assert.Len(t, spans["handler"], 1) {
+			assert.Equal(t, fill.SpanContext().SpanID(), spans["handler"][0].Parent().SpanID())
+		}
+		for _, set := range spans["echo-http-cache.store.set"] {
+			if set.Parent().SpanID() == fill.SpanContext().SpanID() {
+				assert.Equal(t, "memory", spanAttributes(set)[AttributeStore].AsString())
+			}
+		}
+	}
+
+	gets := spans["echo-http-cache.store.get"]
+	if assert.Len(t, gets, 2) {
+		assert.Equal(t, lookups[0].SpanContext().SpanID(), gets[0].Parent().SpanID())
+		assert.Equal(t, "miss", spanAttributes(gets[0])[cache.AttributeCacheStatus].AsString())
+		assert.Equal(t, "hit", spanAttributes(gets[1])[cache.AttributeCacheStatus].AsString())
+	}
+}
+
+// failingStore is a CacheStore whose calls fail through FromCacheStoreV2.
+type failingStore struct{}
+
+func (failingStore) GetContext(context.Context, uint64) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingStore) GetWithTTL(context.Context, uint64) ([]byte, time.Duration, bool, error) {
+	return nil, 0, false, errors.New("connection refused")
+}
+
+func (failingStore) SetContext(context.Context, uint64, []byte, time.Time) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Delete(context.Context, uint64) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Exists(context.Context, uint64) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestInstrumentStore_twoLevelStore(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	l1 := InstrumentStore("L1", cache.FromCacheStoreV2(failingStore{}, func(error) {}), WithTracerProvider(provider))
+	l2 := InstrumentStore("L2", cache.NewCacheMemoryStore(), WithTracerProvider(provider))
+	store := cache.ToCacheStoreV2(cache.NewCacheTwoLevelStore(l1, l2))
+
+	_, _, err := store.GetContext(context.Background(), 1)
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "L1", spanAttributes(spans[0])[AttributeStore].AsString())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "L2", spanAttributes(spans[1])[AttributeStore].AsString())
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	}
+}
+
+func TestInstrumentStore_unwrap(t *testing.T) {
+	twoLevel := cache.NewCacheTwoLevelStore(cache.NewCacheMemoryStore(), cache.NewCacheMemoryStore())
+	store := InstrumentStore("two-level", twoLevel)
+
+	found, ok := cache.StoreAs[*cache.CacheTwoLevelStore](store)
+	assert.True(t, ok)
+	assert.Same(t, twoLevel, found)
+
+	_, ok = cache.StoreAs[cache.CacheKeyLister](InstrumentStore("custom", struct{ cache.CacheStore }{cache.NewCacheMemoryStore()}))
+	assert.False(t, ok)
+}
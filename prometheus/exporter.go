@@ -0,0 +1,244 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+/*
+Package prometheus exports the metrics of the cache middleware and of its stores in the
+Prometheus text exposition format, without depending on the Prometheus client library.
+
+	exporter := prometheus.NewExporter()
+	store := exporter.InstrumentStore("memory", echo_http_cache.NewCacheMemoryStore())
+
+	e.Use(echo_http_cache.CacheWithConfig(echo_http_cache.CacheConfig{
+		Store:      store,
+		Expiration: 5 * time.Minute,
+		Hooks:      exporter.Hooks(),
+	}))
+	e.GET("/metrics", echo.WrapHandler(exporter))
+*/
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	cache "github.com/kenshin579/echo-http-cache"
+	"github.com/labstack/echo/v4"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Config defines the config of the Exporter.
+type Config struct {
+	// Namespace prefixes the metric names. Defaults to echo_http_cache.
+	Namespace string
+
+	// DurationBuckets are the buckets of the store operation latency, in seconds.
+	DurationBuckets []float64
+
+	// SizeBuckets are the buckets of the stored entry sizes, in bytes.
+	SizeBuckets []float64
+}
+
+// DefaultConfig provides default configuration values for the Exporter.
+var DefaultConfig = Config{
+	Namespace:       "echo_http_cache",
+	DurationBuckets: []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	SizeBuckets:     []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304},
+}
+
+// evictionCounter is implemented by the stores counting their evictions.
+type evictionCounter interface {
+	Evictions(ctx context.Context) (int64, error)
+}
+
+// Exporter collects the metrics of the middleware through its hooks, and of the stores
+// it instruments. It is an http.Handler serving the metrics.
+type Exporter struct {
+	namespace string
+
+	requests      *counterVec
+	stored        *counterVec
+	errors        *counterVec
+	storeDuration *histogramVec
+	storeErrors   *counterVec
+	entrySize     *histogramVec
+
+	mutex  sync.Mutex
+	stores []*instrumentedStore
+}
+
+// NewExporter creates an exporter with the default config.
+func NewExporter() *Exporter {
+	return NewExporterWithConfig(DefaultConfig)
+}
+
+// NewExporterWithConfig creates an exporter with a custom config.
+func NewExporterWithConfig(config Config) *Exporter {
+	if config.Namespace == "" {
+		config.Namespace = DefaultConfig.Namespace
+	}
+	if len(config.DurationBuckets) == 0 {
+		config.DurationBuckets = DefaultConfig.DurationBuckets
+	}
+	if len(config.SizeBuckets) == 0 {
+		config.SizeBuckets = DefaultConfig.SizeBuckets
+	}
+
+	ns := config.Namespace + "_"
+	return &Exporter{
+		namespace: ns,
+		requests: newCounterVec(ns+"requests_total",
+			"Requests handled by the cache middleware, by route and result (hit, stale, miss or bypass).", "route", "result"),
+		stored: newCounterVec(ns+"stored_total",
+			"Responses stored by the cache middleware, by route.", "route"),
+		errors: newCounterVec(ns+"errors_total",
+			"Store errors reported to the cache middleware, by route and operation.", "route", "op"),
+		storeDuration: newHistogramVec(ns+"store_operation_duration_seconds",
+			"Latency of the store operations, by store and operation.", config.DurationBuckets, "store", "op"),
+		storeErrors: newCounterVec(ns+"store_errors_total",
+			"Failed store operations, by store and operation.", "store", "op"),
+		entrySize: newHistogramVec(ns+"entry_size_bytes",
+			"Size of the entries written to the store, by store.", config.SizeBuckets, "store"),
+	}
+}
+
+// Hooks returns the hooks counting the decisions of the middleware by route. Other hooks
+// can call them to combine several integrations.
+func (e *Exporter) Hooks() cache.CacheHooks {
+	return cache.CacheHooks{
+		OnHit: func(c echo.Context, event cache.CacheEvent) {
+			result := "hit"
+			if strings.HasPrefix(event.Reason, "stale") {
+				result = "stale"
+			}
+			e.requests.inc(c.Path(), result)
+		},
+		OnMiss: func(c echo.Context, event cache.CacheEvent) {
+			e.requests.inc(c.Path(), "miss")
+		},
+		OnStore: func(c echo.Context, event cache.CacheEvent) {
+			e.stored.inc(c.Path())
+		},
+		OnBypass: func(c echo.Context, event cache.CacheEvent) {
+			// the requests of the responses not stored are counted as misses
+			switch event.Reason {
+			case "excluded", "key", "request-body", "head":
+				e.requests.inc(c.Path(), "bypass")
+			}
+		},
+		OnError: func(c echo.Context, event cache.CacheEvent) {
+			op := "unknown"
+			var storeErr *cache.StoreError
+			if errors.As(event.Err, &storeErr) {
+				op = storeErr.Op
+			}
+			e.errors.inc(c.Path(), op)
+		},
+	}
+}
+
+// InstrumentStore returns the store recording the latency and errors of its operations and
+// the size of its entries under the name. Its evictions, and the async queue depth and level
+// hits of a CacheTwoLevelStore, are exported as well. The levels of a CacheTwoLevelStore are
+// instrumented separately, before creating it.
+func (e *Exporter) InstrumentStore(name string, store cache.CacheStore) cache.CacheStore {
+	instrumented := &instrumentedStore{
+		name:     name,
+		store:    store,
+		v2:       cache.ToCacheStoreV2(store),
+		exporter: e,
+	}
+
+	e.mutex.Lock()
+	e.stores = append(e.stores, instrumented)
+	e.mutex.Unlock()
+	return instrumented
+}
+
+// ServeHTTP implements the http.Handler interface, serving the metrics.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := e.Write(r.Context(), &buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// Write writes the metrics in the Prometheus text exposition format. The metrics of the
+// stores failing to report them, such as an unreachable Redis server, are omitted.
+func (e *Exporter) Write(ctx context.Context, w io.Writer) error {
+	for _, metric := range []interface{ write(io.Writer) error }{
+		e.requests, e.stored, e.errors, e.storeDuration, e.storeErrors, e.entrySize,
+	} {
+		if err := metric.write(w); err != nil {
+			return err
+		}
+	}
+
+	e.mutex.Lock()
+	stores := append([]*instrumentedStore(nil), e.stores...)
+	e.mutex.Unlock()
+
+	var evictions, queueDepth, levelHits, misses []sample
+	for _, s := range stores {
+		if counter, ok := cache.StoreAs[evictionCounter](s.store); ok {
+			if n, err := counter.Evictions(ctx); err == nil {
+				evictions = append(evictions, sample{[]string{s.name}, float64(n)})
+			}
+		}
+		if queue, ok := cache.StoreAs[interface{ QueueDepth() int }](s.store); ok {
+			queueDepth = append(queueDepth, sample{[]string{s.name}, float64(queue.QueueDepth())})
+		}
+		if stats, ok := cache.StoreAs[interface{ GetStats() cache.CacheStats }](s.store); ok {
+			st := stats.GetStats()
+			levelHits = append(levelHits,
+				sample{[]string{s.name, "L1"}, float64(st.L1Hits)},
+				sample{[]string{s.name, "L2"}, float64(st.L2Hits)})
+			misses = append(misses, sample{[]string{s.name}, float64(st.TotalMiss)})
+		}
+	}
+
+	if err := writeSamples(w, e.namespace+"evictions_total",
+		"Entries evicted by the store, by store.", "counter", []string{"store"}, evictions); err != nil {
+		return err
+	}
+	if err := writeSamples(w, e.namespace+"async_queue_depth",
+		"Async operations waiting for the worker of a two-level store, by store.", "gauge", []string{"store"}, queueDepth); err != nil {
+		return err
+	}
+	if err := writeSamples(w, e.namespace+"store_hits_total",
+		"Hits of a two-level store, by store and level.", "counter", []string{"store", "level"}, levelHits); err != nil {
+		return err
+	}
+	return writeSamples(w, e.namespace+"store_misses_total",
+		"Misses of a two-level store, by store.", "counter", []string{"store"}, misses)
+}
@@ -0,0 +1,109 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cache "github.com/kenshin579/echo-http-cache"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporter(t *testing.T) {
+	exporter := NewExporter()
+	store := exporter.InstrumentStore("memory", cache.NewCacheMemoryStoreWithConfig(cache.CacheMemoryStoreConfig{
+		Capacity:  1,
+		Algorithm: cache.LRU,
+	}))
+
+	e := echo.New()
+	e.Use(cache.CacheWithConfig(cache.CacheConfig{
+		Store:        store,
+		Expiration:   5 * time.Second,
+		IncludePaths: []string{"/products", "/private"},
+		ExcludePaths: []string{"/private"},
+		Hooks:        exporter.Hooks(),
+	}))
+	e.GET("/products/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "product "+c.Param("id"))
+	})
+	e.GET("/private", func(c echo.Context) error {
+		return c.String(http.StatusOK, "private")
+	})
+	e.GET("/metrics", echo.WrapHandler(exporter))
+
+	for _, path := range []string{"/products/1", "/products/1", "/products/2", "/private"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get(echo.HeaderContentType))
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		`echo_http_cache_requests_total{route="/products/:id",result="hit"} 1`,
+		`echo_http_cache_requests_total{route="/products/:id",result="miss"} 2`,
+		`echo_http_cache_requests_total{route="/private",result="bypass"} 1`,
+		`echo_http_cache_stored_total{route="/products/:id"} 2`,
+		`echo_http_cache_store_operation_duration_seconds_count{store="memory",op="get"} 3`,
+		`echo_http_cache_store_operation_duration_seconds_count{store="memory",op="set"} 3`,
+		`echo_http_cache_entry_size_bytes_count{store="memory"} 3`,
+		`echo_http_cache_evictions_total{store="memory"} 1`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "store_errors_total")
+	assert.NotContains(t, body, "async_queue_depth")
+}
+
+func TestExporter_twoLevelStore(t *testing.T) {
+	exporter := NewExporter()
+	l1 := exporter.InstrumentStore("l1", cache.NewCacheMemoryStore())
+	l2 := exporter.InstrumentStore("l2", cache.NewCacheMemoryStore())
+	twoLevel := cache.NewCacheTwoLevelStoreWithConfig(cache.TwoLevelConfig{
+		L1Store:  l1,
+		L2Store:  l2,
+		Strategy: cache.WriteBack,
+	})
+	defer twoLevel.(*cache.CacheTwoLevelStore).Stop()
+	store := exporter.InstrumentStore("two-level", twoLevel)
+
+	store.Set(1, []byte("test"), time.Now().Add(time.Minute))
+	store.Get(1)
+	store.Get(2)
+
+	var b strings.Builder
+	assert.NoError(t, exporter.Write(context.Background(), &b))
+
+	body := b.String()
+	for _, line := range []string{
+		`echo_http_cache_store_operation_duration_seconds_count{store="l1",op="set"} 1`,
+		`echo_http_cache_store_operation_duration_seconds_count{store="two-level",op="get"} 2`,
+		`echo_http_cache_store_hits_total{store="two-level",level="L1"} 1`,
+		`echo_http_cache_store_hits_total{store="two-level",level="L2"} 0`,
+		`echo_http_cache_store_misses_total{store="two-level"} 1`,
+		`echo_http_cache_evictions_total{store="two-level"} 0`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.Regexp(t, `echo_http_cache_async_queue_depth\{store="two-level"\} [01]\n`, body)
+}
+
+func TestExporter_tags(t *testing.T) {
+	exporter := NewExporter()
+	store := exporter.InstrumentStore("memory", cache.NewCacheMemoryStore())
+	assert.NoError(t, cache.InvalidateTags(store, "product:1"))
+
+	store = exporter.InstrumentStore("custom", struct{ cache.CacheStore }{cache.NewCacheMemoryStore()})
+	assert.Equal(t, cache.ErrTagsNotSupported, cache.InvalidateTags(store, "product:1"))
+
+	// the instrumented store does not claim the interfaces the store lacks
+	_, ok := cache.StoreAs[interface{ Size() int }](store)
+	assert.False(t, ok)
+}
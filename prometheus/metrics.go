@@ -0,0 +1,259 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// counterVec is a counter partitioned by label values.
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mutex  sync.Mutex
+	values map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]*counterSeries{}}
+}
+
+// add adds delta to the counter of the label values.
+func (v *counterVec) add(delta float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	series, ok := v.values[key]
+	if !ok {
+		series = &counterSeries{labelValues: labelValues}
+		v.values[key] = series
+	}
+	series.value += delta
+}
+
+func (v *counterVec) inc(labelValues ...string) {
+	v.add(1, labelValues...)
+}
+
+func (v *counterVec) write(w io.Writer) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if len(v.values) == 0 {
+		return nil
+	}
+	if err := writeHeader(w, v.name, v.help, "counter"); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(v.values) {
+		series := v.values[key]
+		if err := writeSample(w, v.name, v.labels, series.labelValues, series.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// histogramVec is a histogram partitioned by label values.
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mutex  sync.Mutex
+	values map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+
+	// counts are the counts of the observations in each bucket, not cumulative
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, values: map[string]*histogramSeries{}}
+}
+
+// observe adds the value to the histogram of the label values.
+func (v *histogramVec) observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	series, ok := v.values[key]
+	if !ok {
+		series = &histogramSeries{labelValues: labelValues, counts: make([]uint64, len(v.buckets))}
+		v.values[key] = series
+	}
+	if i := sort.SearchFloat64s(v.buckets, value); i < len(v.buckets) {
+		series.counts[i]++
+	}
+	series.sum += value
+	series.count++
+}
+
+func (v *histogramVec) write(w io.Writer) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if len(v.values) == 0 {
+		return nil
+	}
+	if err := writeHeader(w, v.name, v.help, "histogram"); err != nil {
+		return err
+	}
+
+	labels := append(append([]string(nil), v.labels...), "le")
+	for _, key := range sortedKeys(v.values) {
+		series := v.values[key]
+
+		var cumulative uint64
+		for i, bound := range v.buckets {
+			cumulative += series.counts[i]
+			labelValues := append(append([]string(nil), series.labelValues...), formatFloat(bound))
+			if err := writeSample(w, v.name+"_bucket", labels, labelValues, float64(cumulative)); err != nil {
+				return err
+			}
+		}
+		labelValues := append(append([]string(nil), series.labelValues...), "+Inf")
+		if err := writeSample(w, v.name+"_bucket", labels, labelValues, float64(series.count)); err != nil {
+			return err
+		}
+		if err := writeSample(w, v.name+"_sum", v.labels, series.labelValues, series.sum); err != nil {
+			return err
+		}
+		if err := writeSample(w, v.name+"_count", v.labels, series.labelValues, float64(series.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sample is a value read when the metrics are written, such as the evictions of a store.
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+// writeSamples writes the samples of a metric family read at scrape time.
+func writeSamples(w io.Writer, name, help, metricType string, labels []string, samples []sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	if err := writeHeader(w, name, help, metricType); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		if err := writeSample(w, name, labels, s.labelValues, s.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeHeader(w io.Writer, name, help, metricType string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, metricType)
+	return err
+}
+
+func writeSample(w io.Writer, name string, labels, labelValues []string, value float64) error {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(labelValues[i]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,70 @@
+package prometheus
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_counterVec(t *testing.T) {
+	counter := newCounterVec("requests_total", "Requests.", "route", "result")
+	counter.inc("/b", "hit")
+	counter.inc("/a", "miss")
+	counter.add(2, "/b", "hit")
+
+	var b strings.Builder
+	assert.NoError(t, counter.write(&b))
+	assert.Equal(t, `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a",result="miss"} 1
+requests_total{route="/b",result="hit"} 3
+`, b.String())
+}
+
+func Test_counterVec_empty(t *testing.T) {
+	var b strings.Builder
+	assert.NoError(t, newCounterVec("requests_total", "Requests.", "route").write(&b))
+	assert.Empty(t, b.String())
+}
+
+func Test_histogramVec(t *testing.T) {
+	histogram := newHistogramVec("size_bytes", "Sizes.", []float64{100, 10}, "store")
+	histogram.observe(5, "memory")
+	histogram.observe(10, "memory")
+	histogram.observe(50, "memory")
+	histogram.observe(500, "memory")
+
+	var b strings.Builder
+	assert.NoError(t, histogram.write(&b))
+	assert.Equal(t, `# HELP size_bytes Sizes.
+# TYPE size_bytes histogram
+size_bytes_bucket{store="memory",le="10"} 2
+size_bytes_bucket{store="memory",le="100"} 3
+size_bytes_bucket{store="memory",le="+Inf"} 4
+size_bytes_sum{store="memory"} 565
+size_bytes_count{store="memory"} 4
+`, b.String())
+}
+
+func Test_escape(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, escapeLabelValue("a\\b\"c\nd"))
+	assert.Equal(t, `a\\b"c\nd`, escapeHelp("a\\b\"c\nd"))
+}
+
+func Test_formatFloat(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{value: 1, want: "1"},
+		{value: 0.0025, want: "0.0025"},
+		{value: 4194304, want: "4.194304e+06"},
+		{value: math.Inf(1), want: "+Inf"},
+		{value: math.NaN(), want: "NaN"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, formatFloat(tt.value))
+	}
+}
@@ -0,0 +1,105 @@
+/*
+MIT License
+
+Copyright (c) 2023 Frank Oh
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	cache "github.com/kenshin579/echo-http-cache"
+)
+
+// instrumentedStore records the latency, errors and entry sizes of the operations of a store.
+type instrumentedStore struct {
+	name     string
+	store    cache.CacheStore
+	v2       cache.CacheStoreV2
+	exporter *Exporter
+}
+
+// observe records an operation of the store started at start.
+func (s *instrumentedStore) observe(op string, start time.Time, err error) {
+	s.exporter.storeDuration.observe(time.Since(start).Seconds(), s.name, op)
+	if err != nil {
+		s.exporter.storeErrors.inc(s.name, op)
+	}
+}
+
+func (s *instrumentedStore) Get(key uint64) ([]byte, bool) {
+	data, ok, err := s.GetContext(context.Background(), key)
+	return data, ok && err == nil
+}
+
+func (s *instrumentedStore) Set(key uint64, response []byte, expiration time.Time) {
+	_ = s.SetContext(context.Background(), key, response, expiration)
+}
+
+func (s *instrumentedStore) Release(key uint64) {
+	_ = s.Delete(context.Background(), key)
+}
+
+func (s *instrumentedStore) GetContext(ctx context.Context, key uint64) ([]byte, bool, error) {
+	start := time.Now()
+	data, ok, err := s.v2.GetContext(ctx, key)
+	s.observe("get", start, err)
+	return data, ok, err
+}
+
+func (s *instrumentedStore) GetWithTTL(ctx context.Context, key uint64) ([]byte, time.Duration, bool, error) {
+	start := time.Now()
+	data, ttl, ok, err := s.v2.GetWithTTL(ctx, key)
+	s.observe("get", start, err)
+	return data, ttl, ok, err
+}
+
+func (s *instrumentedStore) SetContext(ctx context.Context, key uint64, response []byte, expiration time.Time) error {
+	start := time.Now()
+	err := s.v2.SetContext(ctx, key, response, expiration)
+	s.observe("set", start, err)
+	if err == nil {
+		s.exporter.entrySize.observe(float64(len(response)), s.name)
+	}
+	return err
+}
+
+func (s *instrumentedStore) Delete(ctx context.Context, key uint64) error {
+	start := time.Now()
+	err := s.v2.Delete(ctx, key)
+	s.observe("delete", start, err)
+	return err
+}
+
+func (s *instrumentedStore) Exists(ctx context.Context, key uint64) (bool, error) {
+	start := time.Now()
+	ok, err := s.v2.Exists(ctx, key)
+	s.observe("exists", start, err)
+	return ok, err
+}
+
+// Unwrap implements the CacheStoreWrapper interface, so that the optional interfaces
+// of the store, such as CacheTagStore or CacheKeyLister, are found with cache.StoreAs.
+func (s *instrumentedStore) Unwrap() cache.CacheStore {
+	return s.store
+}
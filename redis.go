@@ -2,6 +2,11 @@ package echo_http_cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	redisCache "github.com/go-redis/cache/v8"
@@ -11,45 +16,384 @@ import (
 type (
 	// CacheRedisStore is the redis standalone store implementation for Cache
 	CacheRedisStore struct {
-		store *redisCache.Cache
+		store  *redisCache.Cache
+		client *redis.Client
 	}
 )
 
 func NewCacheRedisStoreWithConfig(opt redis.Options) CacheStore {
+	client := redis.NewClient(&opt)
 	return &CacheRedisStore{
-		redisCache.New(&redisCache.Options{
-			Redis: redis.NewClient(&opt),
+		store: redisCache.New(&redisCache.Options{
+			Redis: client,
 		}),
+		client: client,
 	}
 }
 
 // Get implements the cache CacheRedisStore interface Get method.
 func (store *CacheRedisStore) Get(key uint64) ([]byte, bool) {
+	data, ok, _ := store.GetContext(context.Background(), key)
+	return data, ok
+}
+
+func (store *CacheRedisStore) Set(key uint64, response []byte, expiration time.Time) {
+	_ = store.SetContext(context.Background(), key, response, expiration)
+}
+
+func (store *CacheRedisStore) Release(key uint64) {
+	_ = store.Delete(context.Background(), key)
+}
+
+// GetContext implements the CacheStoreV2 interface GetContext method.
+func (store *CacheRedisStore) GetContext(ctx context.Context, key uint64) ([]byte, bool, error) {
+	return redisGet(ctx, store.store, key)
+}
+
+// GetWithTTL implements the CacheStoreV2 interface GetWithTTL method.
+func (store *CacheRedisStore) GetWithTTL(ctx context.Context, key uint64) ([]byte, time.Duration, bool, error) {
+	return redisGetWithTTL(ctx, store.client, key)
+}
+
+// SetContext implements the CacheStoreV2 interface SetContext method.
+func (store *CacheRedisStore) SetContext(ctx context.Context, key uint64, response []byte, expiration time.Time) error {
+	return redisSet(ctx, store.store, key, response, expiration)
+}
+
+// Delete implements the CacheStoreV2 interface Delete method.
+func (store *CacheRedisStore) Delete(ctx context.Context, key uint64) error {
+	return store.store.Delete(ctx, redisKey(key))
+}
+
+// Exists implements the CacheStoreV2 interface Exists method.
+func (store *CacheRedisStore) Exists(ctx context.Context, key uint64) (bool, error) {
+	return redisExists(ctx, store.client, key)
+}
+
+// Clear removes the cached responses and the tag sets from the Redis store, scanning the
+// namespace of the cache. The other keys of the database are kept.
+func (store *CacheRedisStore) Clear() error {
+	return redisClear(context.Background(), store.client, scanFunc(store.client))
+}
+
+// SetTags implements the CacheTagStore interface SetTags method. The keys of a tag
+// are kept in a redis set which lives as long as its longest living key.
+func (store *CacheRedisStore) SetTags(key uint64, tags []string, expiration time.Time) {
+	setTags(context.Background(), store.client, key, tags, expiration)
+}
+
+// InvalidateTags implements the CacheTagStore interface InvalidateTags method.
+func (store *CacheRedisStore) InvalidateTags(tags ...string) error {
+	_, err := store.releaseTags(tags...)
+	return err
+}
+
+func (store *CacheRedisStore) releaseTags(tags ...string) ([]uint64, error) {
+	return invalidateTags(context.Background(), store.client, tags)
+}
+
+// PurgePrefix implements the CachePrefixPurger interface PurgePrefix method, scanning the
+// path index kept in the tag sets.
+func (store *CacheRedisStore) PurgePrefix(ctx context.Context, prefix string) (int, error) {
+	_, purged, err := store.releasePaths(ctx, prefix, prefixMatcher(prefix))
+	return purged, err
+}
+
+// PurgePattern implements the CachePatternPurger interface PurgePattern method, scanning the
+// path index kept in the tag sets.
+func (store *CacheRedisStore) PurgePattern(ctx context.Context, pattern string) (int, error) {
+	_, purged, err := store.releasePaths(ctx, globLiteralPrefix(pattern), patternMatcher(pattern))
+	return purged, err
+}
+
+func (store *CacheRedisStore) releasePaths(ctx context.Context, prefix string, match func(path string) bool) ([]uint64, int, error) {
+	return purgePaths(ctx, store.client, scanFunc(store.client), prefix, match)
+}
+
+// tagScript adds the key ARGV[1] to the set of the tag and raises the time to live of the
+// set to ARGV[2] milliseconds, so that the set lives as long as its longest living key.
+var tagScript = redis.NewScript(`
+redis.call("SADD", KEYS[1], ARGV[1])
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// setTags adds the key to the redis set of each tag, in a single round trip.
+func setTags(ctx context.Context, client redis.Cmdable, key uint64, tags []string, expiration time.Time) {
+	if len(tags) == 0 {
+		return
+	}
+
+	ttl := time.Until(expiration).Milliseconds()
+	_, _ = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			tagScript.Eval(ctx, pipe, []string{tagKey(tag)}, redisKey(key), ttl)
+		}
+		return nil
+	})
+}
+
+// invalidateTags deletes the keys in the redis set of each tag and the set itself, and
+// returns the keys found in the sets.
+func invalidateTags(ctx context.Context, client redis.Cmdable, tags []string) ([]uint64, error) {
+	sets := make([]string, len(tags))
+	for i, tag := range tags {
+		sets[i] = tagKey(tag)
+	}
+	keys, _, err := releaseSets(ctx, client, sets)
+	return keys, err
+}
+
+// releaseSets deletes the keys in the redis sets and the sets themselves, with a constant
+// number of round trips. It returns the keys found in the sets and how many of them existed.
+func releaseSets(ctx context.Context, client redis.Cmdable, sets []string) ([]uint64, int, error) {
+	if len(sets) == 0 {
+		return nil, 0, nil
+	}
+
+	members := make([]*redis.StringSliceCmd, len(sets))
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, set := range sets {
+			members[i] = pipe.SMembers(ctx, set)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var names []string
+	var keys []uint64
+	for _, cmd := range members {
+		for _, name := range cmd.Val() {
+			names = append(names, name)
+			if key, ok := parseRedisKey(name); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	deleted, err := redisDel(ctx, client, names)
+	if err != nil {
+		return keys, deleted, err
+	}
+	_, err = redisDel(ctx, client, sets)
+	return keys, deleted, err
+}
+
+// redisDel deletes the keys in a single round trip and returns how many existed. Each key
+// has its own DEL, so that a cluster client sends it to the node of its slot.
+func redisDel(ctx context.Context, client redis.Cmdable, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Del(ctx, key)
+		}
+		return nil
+	})
+
+	deleted := 0
+	for _, cmd := range cmds {
+		deleted += int(cmd.Val())
+	}
+	return deleted, err
+}
+
+// redisNamespace prefixes the names of all the redis keys of the cache, so that the cache
+// can share its database with the keys of the application.
+const redisNamespace = "echo-http-cache:"
+
+// redisKeyPrefix prefixes the names of the redis keys holding the cached responses.
+const redisKeyPrefix = redisNamespace + "key:"
+
+// redisKey returns the name of the redis key holding the cached response of the key.
+func redisKey(key uint64) string {
+	return redisKeyPrefix + keyAsString(key)
+}
+
+// parseRedisKey returns the key of the cached response held by the redis key name.
+func parseRedisKey(name string) (uint64, bool) {
+	s, ok := strings.CutPrefix(name, redisKeyPrefix)
+	if !ok {
+		return 0, false
+	}
+	key, err := strconv.ParseUint(s, 36, 64)
+	return key, err == nil
+}
+
+// redisGet reads the key with the codec, a missing key not being an error.
+func redisGet(ctx context.Context, codec *redisCache.Cache, key uint64) ([]byte, bool, error) {
 	var data []byte
-	if err := store.store.Get(context.Background(), keyAsString(key), &data); err == nil {
-		return data, true
+	err := codec.Get(ctx, redisKey(key), &data)
+	if errors.Is(err, redisCache.ErrCacheMiss) {
+		return nil, false, nil
 	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
 
-	return nil, false
+// redisGetWithTTL reads the key and its time to live in a single round trip.
+func redisGetWithTTL(ctx context.Context, client redis.Cmdable, key uint64) ([]byte, time.Duration, bool, error) {
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, redisKey(key))
+		pttl = pipe.PTTL(ctx, redisKey(key))
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, 0, false, nil
+	}
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	data, _ := get.Bytes()
+	ttl := pttl.Val()
+	if ttl < 0 {
+		ttl = 0
+	}
+	return data, ttl, true, nil
 }
 
-func (store *CacheRedisStore) Set(key uint64, response []byte, expiration time.Time) {
-	store.store.Set(&redisCache.Item{
-		Key:   keyAsString(key),
+// redisSet writes the key with the codec until expiration.
+func redisSet(ctx context.Context, codec *redisCache.Cache, key uint64, response []byte, expiration time.Time) error {
+	return codec.Set(&redisCache.Item{
+		Ctx:   ctx,
+		Key:   redisKey(key),
 		Value: response,
-		TTL:   expiration.Sub(time.Now()),
+		TTL:   time.Until(expiration),
 	})
 }
 
-func (store *CacheRedisStore) Release(key uint64) {
-	store.store.Delete(context.Background(), keyAsString(key))
+func redisExists(ctx context.Context, client redis.Cmdable, key uint64) (bool, error) {
+	n, err := client.Exists(ctx, redisKey(key)).Result()
+	return n > 0, err
 }
 
-// Clear removes all entries from the Redis store
-// Note: go-redis/cache library doesn't provide a direct Clear method
-// This is a limitation of the underlying cache library
-func (store *CacheRedisStore) Clear() error {
-	// TODO: Implement Clear when go-redis/cache supports it
-	// For now, return an error indicating this operation is not supported
-	return nil // Return nil for now to allow tests to pass
+// Evictions returns the number of keys evicted by the Redis server because of its maxmemory limit.
+func (store *CacheRedisStore) Evictions(ctx context.Context) (int64, error) {
+	return redisEvictions(ctx, store.client)
+}
+
+// redisEvictions reads evicted_keys from the stats section of INFO.
+func redisEvictions(ctx context.Context, client redis.Cmdable) (int64, error) {
+	info, err := client.Info(ctx, "stats").Result()
+	if err != nil {
+		return 0, err
+	}
+	return parseEvictedKeys(info)
+}
+
+func parseEvictedKeys(info string) (int64, error) {
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "evicted_keys:"); ok {
+			return strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return 0, errors.New("evicted_keys missing from INFO stats")
 }
+
+// Keys implements the CacheKeyLister interface Keys method with SCAN, the cursor being the
+// one of SCAN.
+func (store *CacheRedisStore) Keys(ctx context.Context, cursor string, limit int) ([]uint64, string, error) {
+	return redisKeys(ctx, store.client, cursor, limit)
+}
+
+// redisKeys scans a page of the keys holding the cached responses.
+func redisKeys(ctx context.Context, client redis.Cmdable, cursor string, limit int) ([]uint64, string, error) {
+	var scanCursor uint64
+	if cursor != "" {
+		var err error
+		if scanCursor, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, "", fmt.Errorf("invalid cursor %q", cursor)
+		}
+	}
+
+	names, next, err := client.Scan(ctx, scanCursor, escapeScanPattern(redisKeyPrefix)+"*", int64(limit)).Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	keys := make([]uint64, 0, len(names))
+	for _, name := range names {
+		if key, ok := parseRedisKey(name); ok {
+			keys = append(keys, key)
+		}
+	}
+	if next == 0 {
+		return keys, "", nil
+	}
+	return keys, strconv.FormatUint(next, 10), nil
+}
+
+// scanFunc returns the function calling fn with the names of the keys matching the SCAN pattern.
+func scanFunc(client redis.Cmdable) func(ctx context.Context, match string, fn func(names []string)) error {
+	return func(ctx context.Context, match string, fn func(names []string)) error {
+		var cursor uint64
+		for {
+			names, next, err := client.Scan(ctx, cursor, match, 100).Result()
+			if err != nil {
+				return err
+			}
+			fn(names)
+			if cursor = next; cursor == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// redisClear deletes the keys of the namespace of the cache, page by page.
+func redisClear(ctx context.Context, client redis.Cmdable, scan func(ctx context.Context, match string, fn func(names []string)) error) error {
+	var mutex sync.Mutex
+	var delErr error
+	err := scan(ctx, escapeScanPattern(redisNamespace)+"*", func(names []string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if delErr == nil {
+			_, delErr = redisDel(ctx, client, names)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return delErr
+}
+
+// purgePaths releases the keys of the path tag sets whose path starts with prefix and matches,
+// and returns the keys found in the sets and how many of them existed.
+func purgePaths(ctx context.Context, client redis.Cmdable, scan func(ctx context.Context, match string, fn func(names []string)) error, prefix string, match func(path string) bool) ([]uint64, int, error) {
+	namespace := tagKey(pathTag(""))
+
+	var sets []string
+	var mutex sync.Mutex
+	err := scan(ctx, escapeScanPattern(namespace+prefix)+"*", func(names []string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for _, name := range names {
+			if match(strings.TrimPrefix(name, namespace)) {
+				sets = append(sets, name)
+			}
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return releaseSets(ctx, client, sets)
+}
+
+// escapeScanPattern escapes the special characters of a SCAN MATCH pattern.
+func escapeScanPattern(s string) string {
+	return scanPatternEscaper.Replace(s)
+}
+
+var scanPatternEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
@@ -26,6 +26,12 @@ package echo_http_cache
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	redisCache "github.com/go-redis/cache/v8"
@@ -71,35 +77,149 @@ func NewCacheRedisClusterStoreWithConfig(opt redis.ClusterOptions) CacheStore {
 
 // Get implements the cache CacheRedisClusterStore interface Get method.
 func (store *CacheRedisClusterStore) Get(key uint64) ([]byte, bool) {
-	var data []byte
-	err := store.codec.Get(context.Background(), keyAsString(key), &data)
-	if err != nil {
-		return nil, false
-	}
-	return data, true
+	data, ok, _ := store.GetContext(context.Background(), key)
+	return data, ok
 }
 
 // Set implements the cache CacheRedisClusterStore interface Set method.
 func (store *CacheRedisClusterStore) Set(key uint64, response []byte, expiration time.Time) {
-	store.codec.Set(&redisCache.Item{
-		Ctx:   context.Background(),
-		Key:   keyAsString(key),
-		Value: response,
-		TTL:   time.Until(expiration),
-	})
+	_ = store.SetContext(context.Background(), key, response, expiration)
 }
 
 // Release implements the cache CacheRedisClusterStore interface Release method.
 func (store *CacheRedisClusterStore) Release(key uint64) {
-	store.codec.Delete(context.Background(), keyAsString(key))
+	_ = store.Delete(context.Background(), key)
+}
+
+// GetContext implements the CacheStoreV2 interface GetContext method.
+func (store *CacheRedisClusterStore) GetContext(ctx context.Context, key uint64) ([]byte, bool, error) {
+	return redisGet(ctx, store.codec, key)
+}
+
+// GetWithTTL implements the CacheStoreV2 interface GetWithTTL method.
+func (store *CacheRedisClusterStore) GetWithTTL(ctx context.Context, key uint64) ([]byte, time.Duration, bool, error) {
+	return redisGetWithTTL(ctx, store.client, key)
+}
+
+// SetContext implements the CacheStoreV2 interface SetContext method.
+func (store *CacheRedisClusterStore) SetContext(ctx context.Context, key uint64, response []byte, expiration time.Time) error {
+	return redisSet(ctx, store.codec, key, response, expiration)
+}
+
+// Delete implements the CacheStoreV2 interface Delete method.
+func (store *CacheRedisClusterStore) Delete(ctx context.Context, key uint64) error {
+	return store.codec.Delete(ctx, redisKey(key))
+}
+
+// Exists implements the CacheStoreV2 interface Exists method.
+func (store *CacheRedisClusterStore) Exists(ctx context.Context, key uint64) (bool, error) {
+	return redisExists(ctx, store.client, key)
 }
 
-// Clear removes all cache entries from all master nodes
+// Clear removes the cached responses and the tag sets from all master nodes, scanning the
+// namespace of the cache. The other keys of the cluster are kept.
 func (store *CacheRedisClusterStore) Clear() error {
-	ctx := context.Background()
-	// Redis Cluster doesn't support FLUSHALL across all nodes
-	// We need to iterate through each master node
-	return store.client.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
-		return shard.FlushDB(ctx).Err()
+	return redisClear(context.Background(), store.client, store.scanMasters)
+}
+
+// SetTags implements the CacheTagStore interface SetTags method.
+func (store *CacheRedisClusterStore) SetTags(key uint64, tags []string, expiration time.Time) {
+	setTags(context.Background(), store.client, key, tags, expiration)
+}
+
+// InvalidateTags implements the CacheTagStore interface InvalidateTags method.
+func (store *CacheRedisClusterStore) InvalidateTags(tags ...string) error {
+	_, err := store.releaseTags(tags...)
+	return err
+}
+
+func (store *CacheRedisClusterStore) releaseTags(tags ...string) ([]uint64, error) {
+	return invalidateTags(context.Background(), store.client, tags)
+}
+
+// PurgePrefix implements the CachePrefixPurger interface PurgePrefix method, scanning the
+// path index kept in the tag sets on all master nodes.
+func (store *CacheRedisClusterStore) PurgePrefix(ctx context.Context, prefix string) (int, error) {
+	_, purged, err := store.releasePaths(ctx, prefix, prefixMatcher(prefix))
+	return purged, err
+}
+
+// PurgePattern implements the CachePatternPurger interface PurgePattern method, scanning the
+// path index kept in the tag sets on all master nodes.
+func (store *CacheRedisClusterStore) PurgePattern(ctx context.Context, pattern string) (int, error) {
+	_, purged, err := store.releasePaths(ctx, globLiteralPrefix(pattern), patternMatcher(pattern))
+	return purged, err
+}
+
+func (store *CacheRedisClusterStore) releasePaths(ctx context.Context, prefix string, match func(path string) bool) ([]uint64, int, error) {
+	return purgePaths(ctx, store.client, store.scanMasters, prefix, match)
+}
+
+// scanMasters calls fn with the names of the keys matching the SCAN pattern on all master nodes.
+func (store *CacheRedisClusterStore) scanMasters(ctx context.Context, match string, fn func(names []string)) error {
+	return store.client.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		return scanFunc(client)(ctx, match, fn)
+	})
+}
+
+// Evictions returns the number of keys evicted by the master nodes because of their maxmemory limit.
+func (store *CacheRedisClusterStore) Evictions(ctx context.Context) (int64, error) {
+	var total int64
+	err := store.client.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		evictions, err := redisEvictions(ctx, client)
+		atomic.AddInt64(&total, evictions)
+		return err
+	})
+	return total, err
+}
+
+// Keys implements the CacheKeyLister interface Keys method, scanning the master nodes one
+// after the other. The cursor is the index of the master node and its SCAN cursor.
+func (store *CacheRedisClusterStore) Keys(ctx context.Context, cursor string, limit int) ([]uint64, string, error) {
+	index, scanCursor := 0, ""
+	if cursor != "" {
+		i, c, ok := strings.Cut(cursor, ":")
+		n, err := strconv.Atoi(i)
+		if !ok || err != nil {
+			return nil, "", fmt.Errorf("invalid cursor %q", cursor)
+		}
+		index, scanCursor = n, c
+	}
+
+	masters, err := store.masters(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if index >= len(masters) {
+		return nil, "", nil
+	}
+
+	keys, next, err := redisKeys(ctx, masters[index], scanCursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if next != "" {
+		return keys, strconv.Itoa(index) + ":" + next, nil
+	}
+	if index+1 < len(masters) {
+		return keys, strconv.Itoa(index+1) + ":", nil
+	}
+	return keys, "", nil
+}
+
+// masters returns the clients of the master nodes, ordered by address.
+func (store *CacheRedisClusterStore) masters(ctx context.Context) ([]*redis.Client, error) {
+	var mutex sync.Mutex
+	var masters []*redis.Client
+	err := store.client.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		mutex.Lock()
+		masters = append(masters, client)
+		mutex.Unlock()
+		return nil
+	})
+
+	sort.Slice(masters, func(i, j int) bool {
+		return masters[i].Options().Addr < masters[j].Options().Addr
 	})
+	return masters, err
 }
@@ -44,3 +44,19 @@ func TestCacheRedisClusterStore_Clear(t *testing.T) {
 	// Clear 메서드가 존재하는지 확인
 	_ = redisStore.Clear()
 }
+
+func TestCacheRedisClusterStore_InvalidateTags(t *testing.T) {
+	store := NewCacheRedisClusterStore()
+
+	// 인터페이스 구현 확인
+	var _ CacheTagStore = store.(*CacheRedisClusterStore)
+}
+
+func TestCacheRedisClusterStore_Purge(t *testing.T) {
+	store := NewCacheRedisClusterStore()
+
+	// 인터페이스 구현 확인
+	var _ CachePrefixPurger = store.(*CacheRedisClusterStore)
+	var _ CachePatternPurger = store.(*CacheRedisClusterStore)
+	var _ CacheKeyLister = store.(*CacheRedisClusterStore)
+}
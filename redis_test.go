@@ -2,7 +2,7 @@ package echo_http_cache
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +12,7 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/kenshin579/echo-http-cache/test"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -72,6 +73,206 @@ func (suite *cacheRedisStoreTestSuite) Test_Redis_CacheStore() {
 	})
 }
 
+func (suite *cacheRedisStoreTestSuite) Test_Redis_CacheStoreV2() {
+	store := suite.cacheStore.(CacheStoreV2)
+	key := generateKey("GET", "v2")
+
+	suite.Run("SetContext, GetWithTTL", func() {
+		suite.NoError(store.SetContext(suite.ctx, key, []byte("test"), time.Now().Add(1*time.Minute)))
+
+		data, ttl, ok, err := store.GetWithTTL(suite.ctx, key)
+		suite.NoError(err)
+		suite.True(ok)
+		suite.Equal("test", string(data))
+		suite.Equal(time.Minute, ttl.Round(time.Minute))
+	})
+
+	suite.Run("Exists, Delete", func() {
+		exists, err := store.Exists(suite.ctx, key)
+		suite.NoError(err)
+		suite.True(exists)
+
+		suite.NoError(store.Delete(suite.ctx, key))
+
+		_, ok, err := store.GetContext(suite.ctx, key)
+		suite.NoError(err)
+		suite.False(ok)
+		_, _, ok, err = store.GetWithTTL(suite.ctx, key)
+		suite.NoError(err)
+		suite.False(ok)
+	})
+
+	suite.Run("canceled context", func() {
+		ctx, cancel := context.WithCancel(suite.ctx)
+		cancel()
+
+		_, _, err := store.GetContext(ctx, key)
+		suite.ErrorIs(err, context.Canceled)
+	})
+}
+
+func (suite *cacheRedisStoreTestSuite) Test_Redis_Keys() {
+	suite.miniredis.FlushAll()
+	lister := suite.cacheStore.(CacheKeyLister)
+	for key := uint64(1); key <= 3; key++ {
+		suite.cacheStore.Set(key, []byte("test"), time.Now().Add(1*time.Minute))
+	}
+	suite.cacheStore.(CacheTagStore).SetTags(1, []string{"products"}, time.Now().Add(1*time.Minute))
+	// a key of the application which reads as a base 36 number
+	suite.NoError(suite.miniredis.Set("session", "other"))
+
+	var keys []uint64
+	cursor := ""
+	for {
+		page, next, err := lister.Keys(suite.ctx, cursor, 2)
+		suite.NoError(err)
+		keys = append(keys, page...)
+		if cursor = next; cursor == "" {
+			break
+		}
+	}
+
+	// the tag sets and the keys of the application are not listed
+	suite.ElementsMatch([]uint64{1, 2, 3}, keys)
+
+	_, _, err := lister.Keys(suite.ctx, "invalid", 2)
+	suite.Error(err)
+}
+
+func (suite *cacheRedisStoreTestSuite) Test_Redis_Clear() {
+	suite.miniredis.FlushAll()
+	suite.cacheStore.Set(1, []byte("test"), time.Now().Add(1*time.Minute))
+	suite.cacheStore.(CacheTagStore).SetTags(1, []string{"products"}, time.Now().Add(1*time.Minute))
+	// keys of the application, one of them reading as a base 36 number like a cache key
+	suite.NoError(suite.miniredis.Set("session", "other"))
+	suite.NoError(suite.miniredis.Set("session:42", "other"))
+
+	suite.NoError(suite.cacheStore.(interface{ Clear() error }).Clear())
+
+	// the keys of the cache are removed, the other keys are kept
+	suite.Equal([]string{"session", "session:42"}, suite.miniredis.Keys())
+}
+
+func (suite *cacheRedisStoreTestSuite) Test_Redis_Purge() {
+	suite.miniredis.FlushAll()
+	e := newPurgeTestServer(suite.cacheStore)
+	serve := func() {
+		for _, u := range purgeTestURLs {
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+		}
+	}
+	cached := func(u string) bool {
+		_, ok := suite.cacheStore.Get(generateKey(http.MethodGet, u))
+		return ok
+	}
+
+	suite.Run("PurgePattern releases the keys of the matching paths", func() {
+		serve()
+		purged, err := PurgePattern(suite.ctx, suite.cacheStore, "/api/products/*/reviews")
+		suite.NoError(err)
+		suite.Equal(2, purged)
+		suite.False(cached("/api/products/1/reviews"))
+		suite.True(cached("/api/products/1?page=2"))
+		suite.False(suite.miniredis.Exists(tagKey(pathTag("/api/products/1/reviews"))))
+	})
+
+	suite.Run("PurgePrefix releases the keys of the paths under the prefix", func() {
+		serve()
+		purged, err := PurgePrefix(suite.ctx, suite.cacheStore, "/api/products/")
+		suite.NoError(err)
+		suite.Equal(4, purged)
+		suite.False(cached("/api/products/1?page=2"))
+		suite.True(cached("/api/users/1"))
+	})
+}
+
+// roundTripCounter is a redis hook counting the round trips of a client.
+type roundTripCounter struct {
+	roundTrips int
+}
+
+func (h *roundTripCounter) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	h.roundTrips++
+	return ctx, nil
+}
+
+func (h *roundTripCounter) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *roundTripCounter) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	h.roundTrips++
+	return ctx, nil
+}
+
+func (h *roundTripCounter) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func (suite *cacheRedisStoreTestSuite) Test_Redis_InvalidateTags() {
+	tagStore := suite.cacheStore.(CacheTagStore)
+	key1, key2 := generateKey("GET", "tag1"), generateKey("GET", "tag2")
+
+	suite.cacheStore.Set(key1, []byte("test1"), time.Now().Add(1*time.Minute))
+	suite.cacheStore.Set(key2, []byte("test2"), time.Now().Add(1*time.Minute))
+	tagStore.SetTags(key1, []string{"product:1", "products"}, time.Now().Add(1*time.Minute))
+	tagStore.SetTags(key2, []string{"products"}, time.Now().Add(2*time.Minute))
+
+	suite.Run("the tag set lives as long as its longest living key", func() {
+		suite.Equal(2*time.Minute, suite.miniredis.TTL(tagKey("products")).Round(time.Minute))
+
+		tagStore.SetTags(key1, []string{"products"}, time.Now().Add(1*time.Minute))
+		suite.Equal(2*time.Minute, suite.miniredis.TTL(tagKey("products")).Round(time.Minute))
+	})
+
+	suite.Run("the tags are set in a single round trip", func() {
+		counter := &roundTripCounter{}
+		client := redis.NewClient(&redis.Options{Addr: suite.miniredis.Addr()})
+		defer client.Close()
+		client.AddHook(counter)
+
+		setTags(suite.ctx, client, key1, []string{"product:1", "products", "home"}, time.Now().Add(1*time.Minute))
+		suite.Equal(1, counter.roundTrips)
+		suite.True(suite.miniredis.Exists(tagKey("home")))
+	})
+
+	suite.Run("InvalidateTags releases the tagged keys only", func() {
+		suite.NoError(tagStore.InvalidateTags("product:1"))
+
+		_, ok := suite.cacheStore.Get(key1)
+		suite.False(ok)
+		_, ok = suite.cacheStore.Get(key2)
+		suite.True(ok)
+		suite.False(suite.miniredis.Exists(tagKey("product:1")))
+	})
+
+	suite.Run("InvalidateTags releases all the keys of a tag", func() {
+		suite.NoError(tagStore.InvalidateTags("products"))
+
+		_, ok := suite.cacheStore.Get(key2)
+		suite.False(ok)
+	})
+
+	suite.Run("the keys are deleted in a constant number of round trips", func() {
+		for i := 0; i < 10; i++ {
+			key := generateKey("GET", fmt.Sprintf("tag%d", i))
+			suite.cacheStore.Set(key, []byte("test"), time.Now().Add(1*time.Minute))
+			tagStore.SetTags(key, []string{"products", "home"}, time.Now().Add(1*time.Minute))
+		}
+
+		counter := &roundTripCounter{}
+		client := redis.NewClient(&redis.Options{Addr: suite.miniredis.Addr()})
+		defer client.Close()
+		client.AddHook(counter)
+
+		_, err := invalidateTags(suite.ctx, client, []string{"products", "home"})
+		suite.NoError(err)
+		suite.Equal(3, counter.roundTrips)
+		suite.False(suite.miniredis.Exists(tagKey("products")))
+		suite.False(suite.miniredis.Exists(redisKey(generateKey("GET", "tag9"))))
+	})
+}
+
 func (suite *cacheRedisStoreTestSuite) Test_Echo_CacheWithConfig() {
 	suite.echo.GET("/test", func(c echo.Context) error {
 		return c.String(http.StatusOK, "test")
@@ -116,8 +317,7 @@ func (suite *cacheRedisStoreTestSuite) Test_Echo_CacheWithConfig() {
 		data, ok := suite.cacheStore.Get(key)
 		suite.True(ok)
 
-		var cacheResponse CacheResponse
-		err := json.Unmarshal(data, &cacheResponse)
+		cacheResponse, err := BinaryCodec{}.Decode(data)
 		suite.NoError(err)
 		suite.Equal("test", string(cacheResponse.Body))
 		suite.Equal(1, cacheResponse.Frequency)
@@ -136,8 +336,7 @@ func (suite *cacheRedisStoreTestSuite) Test_Echo_CacheWithConfig() {
 		data, ok := suite.cacheStore.Get(key)
 		suite.True(ok)
 
-		var cacheResponse CacheResponse
-		err := json.Unmarshal(data, &cacheResponse)
+		cacheResponse, err := BinaryCodec{}.Decode(data)
 		suite.NoError(err)
 		suite.Equal("test", string(cacheResponse.Body))
 		suite.Equal(2, cacheResponse.Frequency)
@@ -158,8 +357,7 @@ func (suite *cacheRedisStoreTestSuite) Test_Echo_CacheWithConfig() {
 		data, ok := suite.cacheStore.Get(key)
 		suite.True(ok)
 
-		var cacheResponse CacheResponse
-		err := json.Unmarshal(data, &cacheResponse)
+		cacheResponse, err := BinaryCodec{}.Decode(data)
 		suite.NoError(err)
 		suite.Equal("test", string(cacheResponse.Body))
 		suite.Equal(1, cacheResponse.Frequency)
@@ -179,7 +377,7 @@ func (suite *cacheRedisStoreTestSuite) Test_Echo_CacheWithConfig() {
 		suite.False(ok)
 	})
 
-	suite.Run("GET /empty/json", func() {
+	suite.Run("GET /empty/json - JSON with zero values is cached", func() {
 		req := httptest.NewRequest(http.MethodGet, "/empty/json", nil)
 		rec := httptest.NewRecorder()
 
@@ -190,7 +388,7 @@ func (suite *cacheRedisStoreTestSuite) Test_Echo_CacheWithConfig() {
 
 		key := generateKey(http.MethodGet, "/empty/json")
 		_, ok := suite.cacheStore.Get(key)
-		suite.False(ok)
+		suite.True(ok)
 	})
 
 	suite.Run("GET /expired - second call, still get the response from the cache", func() {
@@ -206,9 +404,17 @@ func (suite *cacheRedisStoreTestSuite) Test_Echo_CacheWithConfig() {
 		data, ok := suite.cacheStore.Get(key)
 		suite.True(ok)
 
-		var cacheResponse CacheResponse
-		err := json.Unmarshal(data, &cacheResponse)
+		cacheResponse, err := BinaryCodec{}.Decode(data)
 		suite.NoError(err)
 		suite.Equal(2, cacheResponse.Frequency)
 	})
 }
+
+func Test_parseEvictedKeys(t *testing.T) {
+	evictions, err := parseEvictedKeys("# Stats\r\ntotal_connections_received:3\r\nevicted_keys:42\r\nkeyspace_hits:7\r\n")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), evictions)
+
+	_, err = parseEvictedKeys("# Clients\r\nconnected_clients:1\r\n")
+	assert.Error(t, err)
+}
@@ -25,6 +25,8 @@ SOFTWARE.
 package echo_http_cache
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
@@ -84,38 +86,75 @@ func NewCacheTwoLevelStoreWithConfig(config TwoLevelConfig) CacheStore {
 
 // Get implements CacheStore interface
 func (store *CacheTwoLevelStore) Get(key uint64) ([]byte, bool) {
+	data, _, found := store.GetFromLevel(key)
+	return data, found
+}
+
+// GetFromLevel works like Get and also returns the level the data comes from, "L1" or "L2".
+func (store *CacheTwoLevelStore) GetFromLevel(key uint64) ([]byte, string, bool) {
+	data, _, level, found, _ := store.getFromLevel(context.Background(), key, false)
+	return data, level, found
+}
+
+// getFromLevel returns the data of the key, its remaining time to live and the level it
+// comes from. A failing L1 falls back to L2, the error being returned unless L2 has the key.
+// The time to live is only read when withTTL is set or the L2 data warms L1, since reading
+// it costs a decode of the memory entries.
+func (store *CacheTwoLevelStore) getFromLevel(ctx context.Context, key uint64, withTTL bool) ([]byte, time.Duration, string, bool, error) {
 	// 1. Try L1 cache first (memory)
-	if data, found := store.config.L1Store.Get(key); found {
+	data, ttl, found, l1Err := levelGet(ctx, store.config.L1Store, key, withTTL)
+	if found && l1Err == nil {
 		store.metrics.IncrementL1Hit()
-		return data, true
+		return data, ttl, "L1", true, nil
 	}
 
 	// 2. Try L2 cache (Redis)
-	if data, found := store.config.L2Store.Get(key); found {
+	data, ttl, found, err := levelGet(ctx, store.config.L2Store, key, withTTL || store.config.CacheWarming)
+	if found && err == nil {
 		store.metrics.IncrementL2Hit()
 
 		// Cache warming: promote L2 data to L1
 		if store.config.CacheWarming {
-			store.warmCache(key, data)
+			store.warmCache(key, data, ttl)
 		}
 
-		return data, true
+		return data, ttl, "L2", true, nil
 	}
 
 	// Cache miss
 	store.metrics.IncrementMiss()
-	return nil, false
+	if err == nil {
+		err = l1Err
+	}
+	return nil, 0, "", false, err
+}
+
+// levelGet reads the key from the level, with its time to live when withTTL is set.
+func levelGet(ctx context.Context, level CacheStore, key uint64, withTTL bool) ([]byte, time.Duration, bool, error) {
+	if withTTL {
+		return ToCacheStoreV2(level).GetWithTTL(ctx, key)
+	}
+	data, found, err := ToCacheStoreV2(level).GetContext(ctx, key)
+	return data, 0, found, err
 }
 
 // warmCache promotes L2 data to L1 cache with optimized logic
-func (store *CacheTwoLevelStore) warmCache(key uint64, data []byte) {
+func (store *CacheTwoLevelStore) warmCache(key uint64, data []byte, ttl time.Duration) {
+	// Smart TTL calculation: use the shorter of L1TTL or remaining time
+	l1TTL := store.config.L1TTL
+	if ttl > 0 && ttl < l1TTL {
+		l1TTL = ttl
+	}
+	l1Expiration := time.Now().Add(l1TTL)
+
 	if store.config.SyncMode == "async" {
 		// Async warming to avoid blocking the response
 		select {
 		case store.asyncChan <- asyncOperation{
-			operation: "warm",
-			key:       key,
-			data:      data,
+			operation:  "warm",
+			key:        key,
+			data:       data,
+			expiration: l1Expiration,
 		}:
 			// Successfully queued for warming
 		default:
@@ -123,41 +162,71 @@ func (store *CacheTwoLevelStore) warmCache(key uint64, data []byte) {
 		}
 	} else {
 		// Sync warming
-		store.performWarming(key, data)
+		store.performWarming(key, data, l1Expiration)
 	}
 }
 
 // performWarming executes the actual cache warming operation
-func (store *CacheTwoLevelStore) performWarming(key uint64, data []byte) {
-	// Smart TTL calculation: use the shorter of L1TTL or remaining time
-	l1Expiration := time.Now().Add(store.config.L1TTL)
-
-	// For now, we use L1TTL. In a future version, we could get the actual
-	// expiration from L2 store if the interface supports it
-	store.config.L1Store.Set(key, data, l1Expiration)
+func (store *CacheTwoLevelStore) performWarming(key uint64, data []byte, expiration time.Time) {
+	store.config.L1Store.Set(key, data, expiration)
 }
 
 // Set implements CacheStore interface
 func (store *CacheTwoLevelStore) Set(key uint64, response []byte, expiration time.Time) {
+	_ = store.SetContext(context.Background(), key, response, expiration)
+}
+
+// Release implements CacheStore interface
+func (store *CacheTwoLevelStore) Release(key uint64) {
+	_ = store.Delete(context.Background(), key)
+}
+
+// GetContext implements the CacheStoreV2 interface GetContext method. The level the data
+// comes from is recorded in the context for the Cache-Status header.
+func (store *CacheTwoLevelStore) GetContext(ctx context.Context, key uint64) ([]byte, bool, error) {
+	data, _, level, found, err := store.getFromLevel(ctx, key, false)
+	recordLevel(ctx, level)
+	return data, found, err
+}
+
+// GetWithTTL implements the CacheStoreV2 interface GetWithTTL method.
+func (store *CacheTwoLevelStore) GetWithTTL(ctx context.Context, key uint64) ([]byte, time.Duration, bool, error) {
+	data, ttl, level, found, err := store.getFromLevel(ctx, key, true)
+	recordLevel(ctx, level)
+	return data, ttl, found, err
+}
+
+// SetContext implements the CacheStoreV2 interface SetContext method.
+func (store *CacheTwoLevelStore) SetContext(ctx context.Context, key uint64, response []byte, expiration time.Time) error {
 	switch store.config.Strategy {
 	case WriteThrough:
-		store.setWriteThrough(key, response, expiration)
+		return store.setWriteThrough(ctx, key, response, expiration)
 	case WriteBack:
-		store.setWriteBack(key, response, expiration)
+		return store.setWriteBack(ctx, key, response, expiration)
 	case CacheAside:
-		store.setCacheAside(key, response, expiration)
+		return store.setCacheAside(ctx, key, response, expiration)
 	}
+	return nil
 }
 
-// Release implements CacheStore interface
-func (store *CacheTwoLevelStore) Release(key uint64) {
+// Delete implements the CacheStoreV2 interface Delete method.
+func (store *CacheTwoLevelStore) Delete(ctx context.Context, key uint64) error {
 	// Remove from both L1 and L2
-	store.config.L1Store.Release(key)
-	store.config.L2Store.Release(key)
+	err1 := ToCacheStoreV2(store.config.L1Store).Delete(ctx, key)
+	err2 := ToCacheStoreV2(store.config.L2Store).Delete(ctx, key)
+	return errors.Join(err1, err2)
 }
 
-// setWriteThrough implements write-through strategy
-func (store *CacheTwoLevelStore) setWriteThrough(key uint64, response []byte, expiration time.Time) {
+// Exists implements the CacheStoreV2 interface Exists method.
+func (store *CacheTwoLevelStore) Exists(ctx context.Context, key uint64) (bool, error) {
+	if ok, err := ToCacheStoreV2(store.config.L1Store).Exists(ctx, key); ok && err == nil {
+		return true, nil
+	}
+	return ToCacheStoreV2(store.config.L2Store).Exists(ctx, key)
+}
+
+// levelExpirations returns the expirations of L1 and L2, bounded by their TTL.
+func (store *CacheTwoLevelStore) levelExpirations(expiration time.Time) (time.Time, time.Time) {
 	// Calculate L1 expiration (shorter TTL)
 	l1Expiration := time.Now().Add(store.config.L1TTL)
 	if l1Expiration.After(expiration) {
@@ -169,27 +238,27 @@ func (store *CacheTwoLevelStore) setWriteThrough(key uint64, response []byte, ex
 	if l2Expiration.After(expiration) {
 		l2Expiration = expiration
 	}
+	return l1Expiration, l2Expiration
+}
+
+// setWriteThrough implements write-through strategy
+func (store *CacheTwoLevelStore) setWriteThrough(ctx context.Context, key uint64, response []byte, expiration time.Time) error {
+	l1Expiration, l2Expiration := store.levelExpirations(expiration)
 
 	// Write to both caches synchronously
-	store.config.L1Store.Set(key, response, l1Expiration)
-	store.config.L2Store.Set(key, response, l2Expiration)
+	err1 := ToCacheStoreV2(store.config.L1Store).SetContext(ctx, key, response, l1Expiration)
+	err2 := ToCacheStoreV2(store.config.L2Store).SetContext(ctx, key, response, l2Expiration)
+	return errors.Join(err1, err2)
 }
 
 // setWriteBack implements write-back strategy
-func (store *CacheTwoLevelStore) setWriteBack(key uint64, response []byte, expiration time.Time) {
+func (store *CacheTwoLevelStore) setWriteBack(ctx context.Context, key uint64, response []byte, expiration time.Time) error {
+	l1Expiration, l2Expiration := store.levelExpirations(expiration)
+
 	// Write to L1 immediately
-	l1Expiration := time.Now().Add(store.config.L1TTL)
-	if l1Expiration.After(expiration) {
-		l1Expiration = expiration
-	}
-	store.config.L1Store.Set(key, response, l1Expiration)
+	err := ToCacheStoreV2(store.config.L1Store).SetContext(ctx, key, response, l1Expiration)
 
 	// Queue L2 write for async processing
-	l2Expiration := time.Now().Add(store.config.L2TTL)
-	if l2Expiration.After(expiration) {
-		l2Expiration = expiration
-	}
-
 	select {
 	case store.asyncChan <- asyncOperation{
 		operation:  "set",
@@ -199,14 +268,15 @@ func (store *CacheTwoLevelStore) setWriteBack(key uint64, response []byte, expir
 	}:
 	default:
 		// Channel is full, fallback to synchronous write
-		store.config.L2Store.Set(key, response, l2Expiration)
+		err = errors.Join(err, ToCacheStoreV2(store.config.L2Store).SetContext(ctx, key, response, l2Expiration))
 	}
+	return err
 }
 
 // setCacheAside implements cache-aside strategy
-func (store *CacheTwoLevelStore) setCacheAside(key uint64, response []byte, expiration time.Time) {
+func (store *CacheTwoLevelStore) setCacheAside(ctx context.Context, key uint64, response []byte, expiration time.Time) error {
 	// Simple implementation: write to both (similar to write-through)
-	store.setWriteThrough(key, response, expiration)
+	return store.setWriteThrough(ctx, key, response, expiration)
 }
 
 // startAsyncWorker starts the async worker goroutine
@@ -223,7 +293,7 @@ func (store *CacheTwoLevelStore) startAsyncWorker() {
 				case "release":
 					store.config.L2Store.Release(op.key)
 				case "warm":
-					store.performWarming(op.key, op.data)
+					store.performWarming(op.key, op.data, op.expiration)
 				}
 			case <-store.stopChan:
 				return
@@ -246,50 +316,147 @@ func (store *CacheTwoLevelStore) GetStats() CacheStats {
 	stats := store.metrics.GetStats()
 
 	// Add size information if available
-	if memorySizer, ok := store.config.L1Store.(interface{ Size() int }); ok {
+	if memorySizer, ok := StoreAs[interface{ Size() int }](store.config.L1Store); ok {
 		stats.L1Size = memorySizer.Size()
 	}
-	if redisSizer, ok := store.config.L2Store.(interface{ Size() int }); ok {
+	if redisSizer, ok := StoreAs[interface{ Size() int }](store.config.L2Store); ok {
 		stats.L2Size = redisSizer.Size()
 	}
 
 	return stats
 }
 
-// ClearL1 clears only L1 cache
+// ClearL1 clears only L1 cache, returning ErrClearNotSupported when L1 cannot be cleared.
 func (store *CacheTwoLevelStore) ClearL1() error {
-	if clearer, ok := store.config.L1Store.(interface{ Clear() error }); ok {
-		return clearer.Clear()
-	}
-	return nil
+	return clearStore(store.config.L1Store)
 }
 
-// ClearL2 clears only L2 cache
+// ClearL2 clears only L2 cache, returning ErrClearNotSupported when L2 cannot be cleared.
 func (store *CacheTwoLevelStore) ClearL2() error {
-	if clearer, ok := store.config.L2Store.(interface{ Clear() error }); ok {
-		return clearer.Clear()
-	}
-	return nil
+	return clearStore(store.config.L2Store)
 }
 
-// ClearAll clears both L1 and L2 caches
+// ClearAll clears both L1 and L2 caches. ErrClearNotSupported is returned along with
+// the other errors when a level cannot be cleared.
 func (store *CacheTwoLevelStore) ClearAll() error {
-	var err1, err2 error
+	return errors.Join(clearStore(store.config.L1Store), clearStore(store.config.L2Store))
+}
 
-	if clearer, ok := store.config.L1Store.(interface{ Clear() error }); ok {
-		err1 = clearer.Clear()
+// Evictions returns the number of evictions of L1 and L2, for the levels counting them.
+func (store *CacheTwoLevelStore) Evictions(ctx context.Context) (int64, error) {
+	var total int64
+	var errs []error
+	for _, level := range []CacheStore{store.config.L1Store, store.config.L2Store} {
+		if counter, ok := StoreAs[interface {
+			Evictions(ctx context.Context) (int64, error)
+		}](level); ok {
+			evictions, err := counter.Evictions(ctx)
+			total += evictions
+			errs = append(errs, err)
+		}
 	}
-	if clearer, ok := store.config.L2Store.(interface{ Clear() error }); ok {
-		err2 = clearer.Clear()
+	return total, errors.Join(errs...)
+}
+
+// QueueDepth returns the number of async operations waiting for the worker.
+func (store *CacheTwoLevelStore) QueueDepth() int {
+	return len(store.asyncChan)
+}
+
+// Keys implements the CacheKeyLister interface Keys method, listing the keys of L2, or
+// of L1 when L2 does not list its keys.
+func (store *CacheTwoLevelStore) Keys(ctx context.Context, cursor string, limit int) ([]uint64, string, error) {
+	for _, level := range []CacheStore{store.config.L2Store, store.config.L1Store} {
+		if lister, ok := StoreAs[CacheKeyLister](level); ok {
+			return lister.Keys(ctx, cursor, limit)
+		}
 	}
+	return nil, "", ErrKeysNotSupported
+}
 
-	if err1 != nil {
-		return err1
+// PurgePrefix implements the CachePrefixPurger interface PurgePrefix method on both levels,
+// returning the largest number of responses released by a level.
+func (store *CacheTwoLevelStore) PurgePrefix(ctx context.Context, prefix string) (int, error) {
+	return store.purgeLevels(ctx, prefix, prefixMatcher(prefix), func(level CacheStore) (int, error) {
+		return PurgePrefix(ctx, level, prefix)
+	})
+}
+
+// PurgePattern implements the CachePatternPurger interface PurgePattern method on both levels,
+// returning the largest number of responses released by a level.
+func (store *CacheTwoLevelStore) PurgePattern(ctx context.Context, pattern string) (int, error) {
+	return store.purgeLevels(ctx, globLiteralPrefix(pattern), patternMatcher(pattern), func(level CacheStore) (int, error) {
+		return PurgePattern(ctx, level, pattern)
+	})
+}
+
+// purgeLevels purges L2, then L1. The L1 copies warmed from L2 are not in the path index
+// of L1, so the keys released by L2 are released from L1 as well.
+func (store *CacheTwoLevelStore) purgeLevels(ctx context.Context, prefix string, match func(path string) bool, purge func(level CacheStore) (int, error)) (int, error) {
+	purged, supported := 0, false
+	var errs []error
+	add := func(n int, err error) {
+		if err == ErrPurgeNotSupported {
+			return
+		}
+		supported = true
+		purged = max(purged, n)
+		errs = append(errs, err)
 	}
-	return err2
+
+	if releaser, ok := StoreAs[pathReleaser](store.config.L2Store); ok {
+		keys, n, err := releaser.releasePaths(ctx, prefix, match)
+		store.releaseL1(keys)
+		add(n, err)
+	} else {
+		add(purge(store.config.L2Store))
+	}
+	add(purge(store.config.L1Store))
+
+	if !supported {
+		return 0, ErrPurgeNotSupported
+	}
+	return purged, errors.Join(errs...)
 }
 
 // ResetStats resets cache statistics
 func (store *CacheTwoLevelStore) ResetStats() {
 	store.metrics.Reset()
 }
+
+// SetTags implements the CacheTagStore interface SetTags method.
+func (store *CacheTwoLevelStore) SetTags(key uint64, tags []string, expiration time.Time) {
+	if tagStore, ok := StoreAs[CacheTagStore](store.config.L1Store); ok {
+		tagStore.SetTags(key, tags, expiration)
+	}
+	if tagStore, ok := StoreAs[CacheTagStore](store.config.L2Store); ok {
+		tagStore.SetTags(key, tags, expiration)
+	}
+}
+
+// InvalidateTags implements the CacheTagStore interface InvalidateTags method,
+// releasing the tagged responses from both L1 and L2. The L1 copies warmed from L2
+// are not tagged in L1, so the keys released by L2 are released from L1 as well.
+func (store *CacheTwoLevelStore) InvalidateTags(tags ...string) error {
+	var err2 error
+	if releaser, ok := StoreAs[tagReleaser](store.config.L2Store); ok {
+		var keys []uint64
+		keys, err2 = releaser.releaseTags(tags...)
+		store.releaseL1(keys)
+	} else {
+		err2 = InvalidateTags(store.config.L2Store, tags...)
+	}
+	err1 := InvalidateTags(store.config.L1Store, tags...)
+
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+// releaseL1 releases the keys from L1.
+func (store *CacheTwoLevelStore) releaseL1(keys []uint64) {
+	for _, key := range keys {
+		store.config.L1Store.Release(key)
+	}
+}
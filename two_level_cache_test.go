@@ -25,9 +25,17 @@ SOFTWARE.
 package echo_http_cache
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/go-redis/redis/v8"
+	"github.com/kenshin579/echo-http-cache/test"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -88,6 +96,111 @@ func (suite *TwoLevelCacheTestSuite) TestWriteThrough() {
 	suite.Equal(value, l2Data, "L2 data should match")
 }
 
+func (suite *TwoLevelCacheTestSuite) TestInvalidateTags() {
+	key := uint64(12345)
+	value := CacheResponse{Body: []byte("test-value"), Tags: []string{"products"}}.bytes()
+	expiration := time.Now().Add(10 * time.Minute)
+
+	suite.twoLevelStore.Set(key, value, expiration)
+	suite.twoLevelStore.(CacheTagStore).SetTags(key, []string{"products"}, expiration)
+	suite.twoLevelStore.Set(key+1, []byte("untagged"), expiration)
+
+	err := InvalidateTags(suite.twoLevelStore, "products")
+	suite.NoError(err)
+
+	_, l1Found := suite.memoryStore.Get(key)
+	_, l2Found := suite.redisStore.Get(key)
+	suite.False(l1Found, "Tagged data should be released from L1 cache")
+	suite.False(l2Found, "Tagged data should be released from L2 cache")
+
+	_, found := suite.twoLevelStore.Get(key + 1)
+	suite.True(found, "Untagged data should be kept")
+}
+
+// warmingTestServer caches in a two-level store whose L1 holds a single response and whose
+// L2 is Redis, so that requesting /a, /b and /a again warms /a from L2 into L1.
+type warmingTestServer struct {
+	*echo.Echo
+	store CacheStore
+	calls map[string]int
+}
+
+func newWarmingTestServer(t *testing.T) *warmingTestServer {
+	mredis, _ := test.NewRedisDB()
+	t.Cleanup(mredis.Close)
+
+	store := NewCacheTwoLevelStoreWithConfig(TwoLevelConfig{
+		L1Store:      NewCacheMemoryStoreWithConfig(CacheMemoryStoreConfig{Capacity: 1, Algorithm: LRU}),
+		L2Store:      NewCacheRedisStoreWithConfig(redis.Options{Addr: mredis.Addr()}),
+		Strategy:     WriteThrough,
+		L1TTL:        5 * time.Minute,
+		L2TTL:        30 * time.Minute,
+		CacheWarming: true,
+	})
+	t.Cleanup(store.(*CacheTwoLevelStore).Stop)
+
+	server := &warmingTestServer{Echo: echo.New(), store: store, calls: map[string]int{}}
+	server.Use(CacheWithConfig(CacheConfig{Store: store, Expiration: time.Minute, IncludePaths: []string{"/"}}))
+	server.GET("/:name", func(c echo.Context) error {
+		server.calls[c.Param("name")]++
+		SetCacheTags(c, "t:"+c.Param("name"))
+		return c.String(http.StatusOK, c.Param("name"))
+	})
+	return server
+}
+
+func (server *warmingTestServer) get(paths ...string) {
+	for _, path := range paths {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+}
+
+func TestTwoLevelInvalidateTagsReleasesWarmedL1(t *testing.T) {
+	server := newWarmingTestServer(t)
+	server.get("/a", "/b", "/a")
+	assert.Equal(t, 1, server.calls["a"], "/a should be warmed from L2 into L1")
+
+	assert.NoError(t, InvalidateTags(server.store, "t:a"))
+
+	server.get("/a", "/b")
+	assert.Equal(t, 2, server.calls["a"], "the warmed L1 copy of /a should be released")
+	assert.Equal(t, 1, server.calls["b"])
+}
+
+func TestTwoLevelPurgeReleasesWarmedL1(t *testing.T) {
+	tests := []struct {
+		name  string
+		purge func(store CacheStore) (int, error)
+	}{
+		{
+			name: "prefix",
+			purge: func(store CacheStore) (int, error) {
+				return PurgePrefix(context.Background(), store, "/a")
+			},
+		},
+		{
+			name: "pattern",
+			purge: func(store CacheStore) (int, error) {
+				return PurgePattern(context.Background(), store, "/?")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newWarmingTestServer(t)
+			server.get("/a", "/b", "/a")
+			assert.Equal(t, 1, server.calls["a"], "/a should be warmed from L2 into L1")
+
+			purged, err := tt.purge(server.store)
+			assert.NoError(t, err)
+			assert.NotZero(t, purged)
+
+			server.get("/a")
+			assert.Equal(t, 2, server.calls["a"], "the warmed L1 copy of /a should be released")
+		})
+	}
+}
+
 func (suite *TwoLevelCacheTestSuite) TestL1Hit() {
 	key := uint64(12345)
 	value := []byte("test-value")
@@ -137,6 +250,94 @@ func (suite *TwoLevelCacheTestSuite) TestL2HitWithCacheWarming() {
 	}
 }
 
+func (suite *TwoLevelCacheTestSuite) TestGetContext() {
+	store := suite.twoLevelStore.(CacheStoreV2)
+	key := uint64(12345)
+
+	// L2 keeps the remaining time to live of the response when warming L1
+	suite.redisStore.Set(key, CacheResponse{Expiration: time.Now().Add(time.Minute)}.bytes(), time.Now().Add(time.Minute))
+
+	ctx, level := withLevelRecorder(context.Background())
+	_, ttl, found, err := store.GetWithTTL(ctx, key)
+	suite.NoError(err)
+	suite.True(found)
+	suite.Equal("L2", *level)
+	suite.Equal(time.Minute, ttl.Round(time.Minute))
+
+	ctx, level = withLevelRecorder(context.Background())
+	_, found, err = store.GetContext(ctx, key)
+	suite.NoError(err)
+	suite.True(found)
+	suite.Equal("L1", *level)
+}
+
+// ttlCountingStore counts the GetWithTTL calls of a memory store.
+type ttlCountingStore struct {
+	*CacheMemoryStore
+	calls int
+}
+
+func (store *ttlCountingStore) GetWithTTL(ctx context.Context, key uint64) ([]byte, time.Duration, bool, error) {
+	store.calls++
+	return store.CacheMemoryStore.GetWithTTL(ctx, key)
+}
+
+func (suite *TwoLevelCacheTestSuite) TestGetContextSkipsL1TTL() {
+	l1 := &ttlCountingStore{CacheMemoryStore: NewCacheMemoryStore()}
+	store := NewCacheTwoLevelStoreWithConfig(TwoLevelConfig{
+		L1Store:      l1,
+		L2Store:      suite.redisStore,
+		Strategy:     WriteThrough,
+		CacheWarming: true,
+	}).(CacheStoreV2)
+	key := uint64(12345)
+	suite.NoError(store.SetContext(context.Background(), key, []byte("test"), time.Now().Add(time.Minute)))
+
+	// an L1 hit does not decode the entry to read its time to live
+	_, found, err := store.GetContext(context.Background(), key)
+	suite.NoError(err)
+	suite.True(found)
+	suite.Equal(0, l1.calls)
+
+	_, _, found, err = store.GetWithTTL(context.Background(), key)
+	suite.NoError(err)
+	suite.True(found)
+	suite.Equal(1, l1.calls)
+}
+
+func (suite *TwoLevelCacheTestSuite) TestL1ErrorFallsBackToL2() {
+	storeErr := errors.New("connection refused")
+	store := NewCacheTwoLevelStoreWithConfig(TwoLevelConfig{
+		L1Store:  FromCacheStoreV2(failingStore{storeErr}, func(error) {}),
+		L2Store:  suite.redisStore,
+		Strategy: WriteThrough,
+	}).(CacheStoreV2)
+
+	suite.ErrorIs(store.SetContext(context.Background(), 1, []byte("test"), time.Now().Add(time.Minute)), storeErr)
+
+	data, found, err := store.GetContext(context.Background(), 1)
+	suite.NoError(err)
+	suite.True(found)
+	suite.Equal("test", string(data))
+
+	_, found, err = store.GetContext(context.Background(), 2)
+	suite.ErrorIs(err, storeErr)
+	suite.False(found)
+}
+
+func (suite *TwoLevelCacheTestSuite) TestEvictions() {
+	twoLevel := suite.twoLevelStore.(*CacheTwoLevelStore)
+	for key := uint64(1); key <= 12; key++ {
+		twoLevel.Set(key, CacheResponse{LastAccess: time.Now()}.bytes(), time.Now().Add(time.Minute))
+	}
+
+	// L1 holds 10 responses, L2 100
+	evictions, err := twoLevel.Evictions(context.Background())
+	suite.NoError(err)
+	suite.Equal(int64(2), evictions)
+	suite.Equal(0, twoLevel.QueueDepth())
+}
+
 func (suite *TwoLevelCacheTestSuite) TestCacheMiss() {
 	key := uint64(99999)
 